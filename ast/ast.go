@@ -28,6 +28,7 @@ const (
 	INT Const = iota
 	FLOAT
 	STRING
+	NIL
 )
 
 func (c Const) vkind() {}
@@ -39,19 +40,121 @@ func (c Const) String() string {
 		return "float"
 	case STRING:
 		return "string"
+	case NIL:
+		return "nil"
 	}
 	panic("unreachable")
 }
 
+type Constraint uint8
+
+const (
+	ANY Constraint = iota
+	COMPARABLE
+	NUMERIC
+)
+
+func (c Constraint) String() string {
+	switch c {
+	case ANY:
+		return "any"
+	case COMPARABLE:
+		return "comparable"
+	case NUMERIC:
+		return "numeric"
+	}
+	panic("unreachable")
+}
+
+type TypeParam struct {
+	Name       string
+	Constraint Constraint
+}
+
+func NewTypeParam(name string, constraint Constraint) TypeParam {
+	return TypeParam{
+		Name:       name,
+		Constraint: constraint,
+	}
+}
+
+func typeParamsString(params []TypeParam) string {
+	var str strings.Builder
+	fmt.Fprint(&str, "[")
+	for i, p := range params {
+		fmt.Fprintf(&str, "%s: %s", p.Name, p.Constraint)
+		if i+1 != len(params) {
+			fmt.Fprint(&str, " ")
+		}
+	}
+	fmt.Fprint(&str, "] ")
+	return str.String()
+}
+
+// stmtsString prints a block as a list of its statements
+func stmtsString(stmts []Stmt) string {
+	var str strings.Builder
+	fmt.Fprint(&str, "(")
+	for i, s := range stmts {
+		fmt.Fprint(&str, s.String())
+		if i+1 != len(stmts) {
+			fmt.Fprint(&str, " ")
+		}
+	}
+	fmt.Fprint(&str, ")")
+	return str.String()
+}
+
+// quote quotes s using the escapes understood by the lexer
+func quote(s string) string {
+	var str strings.Builder
+	str.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			str.WriteString(`\"`)
+		case '\\':
+			str.WriteString(`\\`)
+		case '\n':
+			str.WriteString(`\n`)
+		case '\t':
+			str.WriteString(`\t`)
+		case '\r':
+			str.WriteString(`\r`)
+		case '\v':
+			str.WriteString(`\v`)
+		default:
+			str.WriteRune(r)
+		}
+	}
+	str.WriteByte('"')
+	return str.String()
+}
+
+// TypeVar is a reference to a type parameter of an enclosing generic fn
+type TypeVar struct {
+	Name string
+}
+
+func (t TypeVar) vkind() {}
+func (t TypeVar) String() string {
+	return t.Name
+}
+
 type Fn struct {
-	Params []Param
-	Rtype  ValueKind
+	TypeParams []TypeParam
+	Params     []Param
+	Rtype      ValueKind
 }
 
 func (f Fn) vkind() {}
 func (f Fn) String() string {
 	var str strings.Builder
-	fmt.Fprintf(&str, "(fn %s (", f.Rtype.String())
+	fmt.Fprint(&str, "(fn ")
+	if f.TypeParams != nil {
+		fmt.Fprint(&str, typeParamsString(f.TypeParams))
+	}
+	fmt.Fprintf(&str, "%s (", f.Rtype.String())
 	for i, e := range f.Params {
 		fmt.Fprintf(&str, "%s", e.Kind.String())
 		if i+1 != len(f.Params) {
@@ -61,30 +164,67 @@ func (f Fn) String() string {
 	fmt.Fprintf(&str, "))")
 	return str.String()
 }
-func NewFnT(params []Param, rtype ValueKind) Fn {
+func NewFnT(typeParams []TypeParam, params []Param, rtype ValueKind) Fn {
 	return Fn{
-		Params: params,
-		Rtype:  rtype,
+		TypeParams: typeParams,
+		Params:     params,
+		Rtype:      rtype,
 	}
 }
 
+// Upvalue is a local of an enclosing function captured by a closure.
+// ByRef captures share the binding with the enclosing function, every
+// other capture is a copy of the value at the time the closure is created.
+type Upvalue struct {
+	Name  string
+	ByRef bool
+}
+
+type Pos struct {
+	Line int
+	Col  int
+}
+
 type Exprs []Expr
 
 type Expr interface {
 	String() string
+	Position() Pos
 	Accept(v Visitor[any]) any
 }
 
 type Visitor[R any] interface {
-	visitBinary(b Binary) R
-	visitUnary(u Unary) R
-	visitLiteral(l Literal) R
-	visitGrouping(g Grouping) R
-	visitVarDecl(v VarDecl) R
-	visitIfExpr(i IfExpr) R
-	visitAssignment(a Assignment) R
-	visitWhileExpr(w WhileExpr) R
-	visitFnExpr(f FnExpr) R
-	visitCallExpr(c Call) R
-	visitReturnExpr(r Return) R
+	VisitBinary(b Binary) R
+	VisitUnary(u Unary) R
+	VisitLiteral(l Literal) R
+	VisitGrouping(g Grouping) R
+	VisitIfExpr(i IfExpr) R
+	VisitAssignment(a Assignment) R
+	VisitFnExpr(f FnExpr) R
+	VisitCallExpr(c Call) R
+	VisitGet(g Get) R
+	VisitInstantiate(i Instantiate) R
+}
+
+type Stmts []Stmt
+
+// File is a parsed source file, Lines holds its source for error messages
+type File struct {
+	Name  string
+	Lines []string
+	Stmts Stmts
+}
+
+type Stmt interface {
+	String() string
+	Position() Pos
+	Accept(v StmtVisitor[any]) any
+}
+
+type StmtVisitor[R any] interface {
+	VisitExprStmt(e ExprStmt) R
+	VisitVarDecl(v VarDecl) R
+	VisitWhileStmt(w WhileStmt) R
+	VisitReturnStmt(r Return) R
+	VisitImport(i Import) R
 }
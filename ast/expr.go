@@ -21,20 +21,26 @@ import (
 type Assignment struct {
 	Name  string
 	Value Expr
+	Pos   Pos
 }
 
 func (a Assignment) String() string {
 	return fmt.Sprintf("(= %s %s)", a.Name, a.Value.String())
 }
 
+func (a Assignment) Position() Pos {
+	return a.Pos
+}
+
 func (a Assignment) Accept(v Visitor[any]) any {
-	return v.visitAssignment(a)
+	return v.VisitAssignment(a)
 }
 
-func NewAssignment(name string, value Expr) Assignment {
+func NewAssignment(name string, value Expr, pos Pos) Assignment {
 	return Assignment{
 		Name:  name,
 		Value: value,
+		Pos:   pos,
 	}
 }
 
@@ -52,8 +58,12 @@ func (b Binary) String() string {
 		b.Right.String(),
 	)
 }
+func (b Binary) Position() Pos {
+	return Pos{b.Operator.Line, b.Operator.Col}
+}
+
 func (b Binary) Accept(v Visitor[any]) any {
-	return v.visitBinary(b)
+	return v.VisitBinary(b)
 }
 
 func NewBinary(left Expr, operator token.Token, right Expr) Binary {
@@ -77,8 +87,12 @@ func (u Unary) String() string {
 	)
 }
 
+func (u Unary) Position() Pos {
+	return Pos{u.Operator.Line, u.Operator.Col}
+}
+
 func (u Unary) Accept(v Visitor[any]) any {
-	return v.visitUnary(u)
+	return v.VisitUnary(u)
 }
 
 func NewUnary(operator token.Token, right Expr) Unary {
@@ -91,18 +105,26 @@ func NewUnary(operator token.Token, right Expr) Unary {
 type Literal struct {
 	Value string
 	Kind  token.TokenKind
+	Pos   Pos
 }
 
 func (l Literal) String() string {
-	return fmt.Sprint(l.Value)
+	if l.Kind == token.STRING {
+		return quote(l.Value[1 : len(l.Value)-1])
+	}
+	return l.Value
+}
+
+func (l Literal) Position() Pos {
+	return l.Pos
 }
 
 func (l Literal) Accept(v Visitor[any]) any {
-	return v.visitLiteral(l)
+	return v.VisitLiteral(l)
 }
 
-func NewLiteral(value string, kind token.TokenKind) Literal {
-	return Literal{value, kind}
+func NewLiteral(value string, kind token.TokenKind, pos Pos) Literal {
+	return Literal{value, kind, pos}
 }
 
 type Grouping struct {
@@ -110,11 +132,15 @@ type Grouping struct {
 }
 
 func (g Grouping) String() string {
-	return g.Inner.String()
+	return fmt.Sprintf("(group %s)", g.Inner.String())
+}
+
+func (g Grouping) Position() Pos {
+	return g.Inner.Position()
 }
 
 func (g Grouping) Accept(v Visitor[any]) any {
-	return v.visitGrouping(g)
+	return v.VisitGrouping(g)
 }
 
 func NewGrouping(inner Expr) Grouping {
@@ -128,20 +154,21 @@ type Call struct {
 
 func (c Call) String() string {
 	var str strings.Builder
-	fmt.Fprintf(&str, "(%s ", c.Callee.String())
-	for i, arg := range c.Arguments {
-		fmt.Fprint(&str, arg.String())
-		if i != len(c.Arguments)-1 {
-			fmt.Fprint(&str, " ")
-		}
+	fmt.Fprintf(&str, "(call %s", c.Callee.String())
+	for _, arg := range c.Arguments {
+		fmt.Fprintf(&str, " %s", arg.String())
 	}
 	fmt.Fprint(&str, ")")
 
 	return str.String()
 }
 
+func (c Call) Position() Pos {
+	return c.Callee.Position()
+}
+
 func (c Call) Accept(v Visitor[any]) any {
-	return v.visitCallExpr(c)
+	return v.VisitCallExpr(c)
 }
 
 func NewCall(callee Expr, arguments []Expr) Call {
@@ -150,3 +177,163 @@ func NewCall(callee Expr, arguments []Expr) Call {
 		Arguments: arguments,
 	}
 }
+
+type Get struct {
+	Object Expr
+	Name   string
+	Pos    Pos
+}
+
+func (g Get) String() string {
+	return fmt.Sprintf("(. %s %s)", g.Object.String(), g.Name)
+}
+
+func (g Get) Position() Pos {
+	return g.Pos
+}
+
+func (g Get) Accept(v Visitor[any]) any {
+	return v.VisitGet(g)
+}
+
+func NewGet(object Expr, name string, pos Pos) Get {
+	return Get{
+		Object: object,
+		Name:   name,
+		Pos:    pos,
+	}
+}
+
+// Instantiate is a generic fn with explicit type arguments, max[int]
+type Instantiate struct {
+	Fn       Expr
+	TypeArgs []ValueKind
+	Pos      Pos
+}
+
+func (i Instantiate) String() string {
+	var str strings.Builder
+	fmt.Fprintf(&str, "(instantiate %s", i.Fn.String())
+	for _, arg := range i.TypeArgs {
+		fmt.Fprintf(&str, " %s", arg.String())
+	}
+	fmt.Fprint(&str, ")")
+
+	return str.String()
+}
+
+func (i Instantiate) Position() Pos {
+	return i.Pos
+}
+
+func (i Instantiate) Accept(v Visitor[any]) any {
+	return v.VisitInstantiate(i)
+}
+
+func NewInstantiate(fn Expr, typeArgs []ValueKind, pos Pos) Instantiate {
+	return Instantiate{
+		Fn:       fn,
+		TypeArgs: typeArgs,
+		Pos:      pos,
+	}
+}
+
+type IfExpr struct {
+	Condition Expr
+	Body      []Stmt
+	Else_ifs  []IfExpr
+	Else      []Stmt
+	Pos       Pos
+	// End is the position of the end closing the if, it is zero for else
+	// ifs
+	End Pos
+}
+
+func (i IfExpr) String() string {
+	var str strings.Builder
+	fmt.Fprintf(&str, "(if %s %s", i.Condition.String(), stmtsString(i.Body))
+	for _, e := range i.Else_ifs {
+		fmt.Fprintf(&str, " %s", e.String())
+	}
+	if i.Else != nil {
+		fmt.Fprintf(&str, " %s", stmtsString(i.Else))
+	}
+	fmt.Fprint(&str, ")")
+	return str.String()
+}
+
+func (i IfExpr) Position() Pos {
+	return i.Pos
+}
+
+func (i IfExpr) Accept(v Visitor[any]) any {
+	return v.VisitIfExpr(i)
+}
+
+func NewIfExpr(condition Expr, body []Stmt, else_ifs []IfExpr, el []Stmt, pos Pos) IfExpr {
+	return IfExpr{
+		Condition: condition,
+		Body:      body,
+		Else_ifs:  else_ifs,
+		Else:      el,
+		Pos:       pos,
+	}
+}
+
+type Param struct {
+	Name string
+	Kind ValueKind
+}
+
+func NewParam(name string, kind ValueKind) Param {
+	return Param{
+		Name: name,
+		Kind: kind,
+	}
+}
+
+type FnExpr struct {
+	TypeParams []TypeParam
+	Params     []Param
+	Body       []Stmt
+	Rtype      ValueKind
+	Captures   []Upvalue
+	Pos        Pos
+	// End is the position of the end closing the fn
+	End Pos
+}
+
+func (f FnExpr) String() string {
+	var str strings.Builder
+	fmt.Fprint(&str, "(fn ")
+	if f.TypeParams != nil {
+		fmt.Fprint(&str, typeParamsString(f.TypeParams))
+	}
+	fmt.Fprintf(&str, "%s (", f.Rtype.String())
+	for i, e := range f.Params {
+		fmt.Fprintf(&str, "%s: %s", e.Name, e.Kind.String())
+		if i+1 != len(f.Params) {
+			fmt.Fprint(&str, " ")
+		}
+	}
+	fmt.Fprintf(&str, ") %s)", stmtsString(f.Body))
+	return str.String()
+}
+
+func (f FnExpr) Position() Pos {
+	return f.Pos
+}
+
+func (f FnExpr) Accept(v Visitor[any]) any {
+	return v.VisitFnExpr(f)
+}
+
+func NewFn(typeParams []TypeParam, params []Param, body []Stmt, rtype ValueKind, pos Pos) FnExpr {
+	return FnExpr{
+		TypeParams: typeParams,
+		Params:     params,
+		Body:       body,
+		Rtype:      rtype,
+		Pos:        pos,
+	}
+}
@@ -0,0 +1,779 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"zimlit/graphene/token"
+)
+
+// JSONVersion is the version of the JSON schema written by MarshalJSON, it
+// changes whenever a node or field is added, removed or renamed.
+//
+// A file is {"version", "name", "lines", "stmts"}. Every node is an object
+// whose "node" field names its type, the rest of its fields are the fields
+// of the type in snake case with positions as {"line", "col"}, where col
+// counts bytes of the line from 1. Types are
+// objects whose "kind" is int, float, string, nil, var or fn, a missing
+// type is null
+const JSONVersion = 3
+
+// MarshalJSON encodes f as JSON
+func MarshalJSON(f File) ([]byte, error) {
+	e := jsonEncoder{f}
+	lines := f.Lines
+	if lines == nil {
+		lines = []string{}
+	}
+	return json.Marshal(map[string]any{
+		"version": JSONVersion,
+		"name":    f.Name,
+		"lines":   lines,
+		"stmts":   e.stmts(f.Stmts),
+	})
+}
+
+// UnmarshalJSON decodes a file written by MarshalJSON
+func UnmarshalJSON(data []byte) (File, error) {
+	var f struct {
+		Version int
+		Name    string
+		Lines   []string
+		Stmts   []json.RawMessage
+	}
+	err := json.Unmarshal(data, &f)
+	if err != nil {
+		return File{}, err
+	}
+	if f.Version != JSONVersion {
+		return File{}, fmt.Errorf("unsupported AST schema version %d, expected %d", f.Version, JSONVersion)
+	}
+
+	stmts, err := decodeStmts(f.Stmts)
+	if err != nil {
+		return File{}, err
+	}
+	if stmts == nil {
+		stmts = Stmts{}
+	}
+	file := File{Name: f.Name, Lines: f.Lines}
+	m := mapper(func(p Pos) Pos { return file.PosFromByteCol(p.Line, p.Col) })
+	file.Stmts = m.stmts(stmts)
+	return file, nil
+}
+
+type jsonEncoder struct {
+	file File
+}
+
+type object map[string]any
+
+func (e jsonEncoder) expr(expr Expr) any {
+	if expr == nil {
+		return nil
+	}
+	return expr.Accept(e)
+}
+
+func (e jsonEncoder) exprs(exprs []Expr) []any {
+	objs := []any{}
+	for _, expr := range exprs {
+		objs = append(objs, e.expr(expr))
+	}
+	return objs
+}
+
+// stmts keeps the difference between a nil and an empty block, an if has no
+// else when Else is nil
+func (e jsonEncoder) stmts(stmts []Stmt) []any {
+	if stmts == nil {
+		return nil
+	}
+	objs := []any{}
+	for _, stmt := range stmts {
+		objs = append(objs, stmt.Accept(e))
+	}
+	return objs
+}
+
+func (e jsonEncoder) kind(kind ValueKind) any {
+	switch k := kind.(type) {
+	case Const:
+		return object{"kind": k.String()}
+	case TypeVar:
+		return object{"kind": "var", "name": k.Name}
+	case Fn:
+		return object{
+			"kind":        "fn",
+			"type_params": e.typeParams(k.TypeParams),
+			"params":      e.params(k.Params),
+			"return":      e.kind(k.Rtype),
+		}
+	}
+	return nil
+}
+
+func (e jsonEncoder) kinds(kinds []ValueKind) []any {
+	objs := []any{}
+	for _, kind := range kinds {
+		objs = append(objs, e.kind(kind))
+	}
+	return objs
+}
+
+func (e jsonEncoder) params(params []Param) []any {
+	objs := []any{}
+	for _, p := range params {
+		objs = append(objs, object{"name": p.Name, "type": e.kind(p.Kind)})
+	}
+	return objs
+}
+
+// typeParams keeps nil, which marks a fn that is not generic
+func (e jsonEncoder) typeParams(params []TypeParam) []any {
+	if params == nil {
+		return nil
+	}
+	objs := []any{}
+	for _, p := range params {
+		objs = append(objs, object{"name": p.Name, "constraint": p.Constraint.String()})
+	}
+	return objs
+}
+
+func (e jsonEncoder) pos(p Pos) object {
+	return object{"line": p.Line, "col": e.file.ByteCol(p)}
+}
+
+func (e jsonEncoder) VisitBinary(b Binary) any {
+	return object{
+		"node":  "Binary",
+		"op":    b.Operator.Kind.String(),
+		"left":  e.expr(b.Left),
+		"right": e.expr(b.Right),
+		"pos":   e.pos(b.Position()),
+	}
+}
+
+func (e jsonEncoder) VisitUnary(u Unary) any {
+	return object{
+		"node":  "Unary",
+		"op":    u.Operator.Kind.String(),
+		"right": e.expr(u.Right),
+		"pos":   e.pos(u.Position()),
+	}
+}
+
+func (e jsonEncoder) VisitLiteral(l Literal) any {
+	o := object{"node": "Literal", "value": l.Value, "pos": e.pos(l.Pos)}
+	switch l.Kind {
+	case token.INT:
+		o["kind"] = "int"
+	case token.FLOAT:
+		o["kind"] = "float"
+	case token.NIL:
+		o["kind"] = "nil"
+	case token.IDENT:
+		o["kind"] = "ident"
+	case token.STRING:
+		o["kind"] = "string"
+		o["value"] = strings.TrimSuffix(strings.TrimPrefix(l.Value, "\""), "\"")
+	}
+	return o
+}
+
+func (e jsonEncoder) VisitGrouping(g Grouping) any {
+	return object{"node": "Grouping", "inner": e.expr(g.Inner)}
+}
+
+func (e jsonEncoder) VisitIfExpr(i IfExpr) any {
+	else_ifs := []any{}
+	for _, elif := range i.Else_ifs {
+		else_ifs = append(else_ifs, e.VisitIfExpr(elif))
+	}
+	return object{
+		"node":     "IfExpr",
+		"cond":     e.expr(i.Condition),
+		"body":     e.stmts(i.Body),
+		"else_ifs": else_ifs,
+		"else":     e.stmts(i.Else),
+		"pos":      e.pos(i.Pos),
+		"end":      e.pos(i.End),
+	}
+}
+
+func (e jsonEncoder) VisitAssignment(a Assignment) any {
+	return object{
+		"node":  "Assignment",
+		"name":  a.Name,
+		"value": e.expr(a.Value),
+		"pos":   e.pos(a.Pos),
+	}
+}
+
+func (e jsonEncoder) VisitFnExpr(f FnExpr) any {
+	captures := []any{}
+	for _, c := range f.Captures {
+		captures = append(captures, object{"name": c.Name, "by_ref": c.ByRef})
+	}
+	return object{
+		"node":        "FnExpr",
+		"type_params": e.typeParams(f.TypeParams),
+		"params":      e.params(f.Params),
+		"return":      e.kind(f.Rtype),
+		"body":        e.stmts(f.Body),
+		"captures":    captures,
+		"pos":         e.pos(f.Pos),
+		"end":         e.pos(f.End),
+	}
+}
+
+func (e jsonEncoder) VisitCallExpr(c Call) any {
+	return object{
+		"node":   "Call",
+		"callee": e.expr(c.Callee),
+		"args":   e.exprs(c.Arguments),
+	}
+}
+
+func (e jsonEncoder) VisitGet(g Get) any {
+	return object{
+		"node":   "Get",
+		"object": e.expr(g.Object),
+		"name":   g.Name,
+		"pos":    e.pos(g.Pos),
+	}
+}
+
+func (e jsonEncoder) VisitInstantiate(i Instantiate) any {
+	return object{
+		"node":      "Instantiate",
+		"fn":        e.expr(i.Fn),
+		"type_args": e.kinds(i.TypeArgs),
+		"pos":       e.pos(i.Pos),
+	}
+}
+
+func (e jsonEncoder) VisitExprStmt(s ExprStmt) any {
+	return object{"node": "ExprStmt", "expr": e.expr(s.Expr)}
+}
+
+func (e jsonEncoder) VisitVarDecl(v VarDecl) any {
+	return object{
+		"node":  "VarDecl",
+		"name":  v.Name,
+		"type":  e.kind(v.Kind),
+		"mut":   v.is_mut,
+		"pub":   v.is_pub,
+		"value": e.expr(v.Value),
+		"pos":   e.pos(v.Pos),
+	}
+}
+
+func (e jsonEncoder) VisitWhileStmt(w WhileStmt) any {
+	return object{
+		"node": "WhileStmt",
+		"cond": e.expr(w.Cond),
+		"body": e.stmts(w.Body),
+		"pos":  e.pos(w.Pos),
+	}
+}
+
+func (e jsonEncoder) VisitReturnStmt(r Return) any {
+	return object{"node": "Return", "value": e.expr(r.Value), "pos": e.pos(r.Pos)}
+}
+
+func (e jsonEncoder) VisitImport(i Import) any {
+	return object{"node": "Import", "path": i.Path, "pos": e.pos(i.Pos)}
+}
+
+// operators maps the spelling of every operator back to its token kind
+var operators = make(map[string]token.TokenKind)
+
+func init() {
+	for _, kind := range []token.TokenKind{
+		token.PLUS, token.MINUS, token.STAR, token.SLASH, token.PERCENT,
+		token.AMP, token.PIPE, token.CARET, token.TILDE, token.SHL, token.SHR,
+		token.EQEQ, token.NEQ, token.LESS, token.LESSEQ, token.GREATER,
+		token.GREATEREQ, token.BANG,
+	} {
+		operators[kind.String()] = kind
+	}
+}
+
+// fields is a node being decoded
+type fields map[string]json.RawMessage
+
+func (f fields) get(key string, v any) error {
+	raw, ok := f[key]
+	if !ok {
+		return fmt.Errorf("%s is missing %q", f.name(), key)
+	}
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		return fmt.Errorf("%s.%s: %w", f.name(), key, err)
+	}
+	return nil
+}
+
+func (f fields) name() string {
+	var name string
+	json.Unmarshal(f["node"], &name)
+	if name == "" {
+		json.Unmarshal(f["kind"], &name)
+	}
+	return name
+}
+
+func (f fields) pos() (Pos, error) {
+	return f.posAt("pos")
+}
+
+func (f fields) posAt(key string) (Pos, error) {
+	var p struct{ Line, Col int }
+	err := f.get(key, &p)
+	return Pos{Line: p.Line, Col: p.Col}, err
+}
+
+func (f fields) expr(key string) (Expr, error) {
+	var raw json.RawMessage
+	err := f.get(key, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return decodeExpr(raw)
+}
+
+func (f fields) stmts(key string) ([]Stmt, error) {
+	var raws []json.RawMessage
+	err := f.get(key, &raws)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStmts(raws)
+}
+
+func (f fields) kind(key string) (ValueKind, error) {
+	var raw json.RawMessage
+	err := f.get(key, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKind(raw)
+}
+
+func (f fields) params() ([]Param, error) {
+	var raws []fields
+	err := f.get("params", &raws)
+	if err != nil {
+		return nil, err
+	}
+	params := []Param{}
+	for _, raw := range raws {
+		var name string
+		err := raw.get("name", &name)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := raw.kind("type")
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, NewParam(name, kind))
+	}
+	return params, nil
+}
+
+func (f fields) typeParams() ([]TypeParam, error) {
+	var raws []struct{ Name, Constraint string }
+	err := f.get("type_params", &raws)
+	if err != nil || raws == nil {
+		return nil, err
+	}
+	params := []TypeParam{}
+	for _, raw := range raws {
+		param := NewTypeParam(raw.Name, ANY)
+		switch raw.Constraint {
+		case "any":
+		case "comparable":
+			param.Constraint = COMPARABLE
+		case "numeric":
+			param.Constraint = NUMERIC
+		default:
+			return nil, fmt.Errorf("unknown constraint %q", raw.Constraint)
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+func (f fields) operator() (token.Token, error) {
+	var op string
+	err := f.get("op", &op)
+	if err != nil {
+		return token.Token{}, err
+	}
+	kind, ok := operators[op]
+	if !ok {
+		return token.Token{}, fmt.Errorf("%s: unknown operator %q", f.name(), op)
+	}
+	p, err := f.pos()
+	return token.Token{Kind: kind, Literal: op, Line: p.Line, Col: p.Col}, err
+}
+
+func decodeFields(raw json.RawMessage) (fields, error) {
+	var f fields
+	err := json.Unmarshal(raw, &f)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("unexpected null node")
+	}
+	return f, nil
+}
+
+func decodeStmts(raws []json.RawMessage) ([]Stmt, error) {
+	if raws == nil {
+		return nil, nil
+	}
+	stmts := []Stmt{}
+	for _, raw := range raws {
+		stmt, err := decodeStmt(raw)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+func decodeStmt(raw json.RawMessage) (Stmt, error) {
+	f, err := decodeFields(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.name() {
+	case "ExprStmt":
+		expr, err := f.expr("expr")
+		return NewExprStmt(expr), err
+	case "VarDecl":
+		var name string
+		var mut, pub bool
+		err := f.get("name", &name)
+		if err != nil {
+			return nil, err
+		}
+		err = f.get("mut", &mut)
+		if err != nil {
+			return nil, err
+		}
+		err = f.get("pub", &pub)
+		if err != nil {
+			return nil, err
+		}
+		kind, err := f.kind("type")
+		if err != nil {
+			return nil, err
+		}
+		value, err := f.expr("value")
+		if err != nil {
+			return nil, err
+		}
+		p, err := f.pos()
+		v := NewVarDecl(name, kind, value, mut, p)
+		if pub {
+			v = v.Export()
+		}
+		return v, err
+	case "WhileStmt":
+		cond, err := f.expr("cond")
+		if err != nil {
+			return nil, err
+		}
+		body, err := f.stmts("body")
+		if err != nil {
+			return nil, err
+		}
+		p, err := f.pos()
+		return NewWhileStmt(cond, body, p), err
+	case "Return":
+		value, err := f.expr("value")
+		if err != nil {
+			return nil, err
+		}
+		p, err := f.pos()
+		return NewReturn(value, p), err
+	case "Import":
+		var path string
+		err := f.get("path", &path)
+		if err != nil {
+			return nil, err
+		}
+		p, err := f.pos()
+		return NewImport(path, p), err
+	}
+	return nil, fmt.Errorf("unknown statement %q", f.name())
+}
+
+func decodeExpr(raw json.RawMessage) (Expr, error) {
+	f, err := decodeFields(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.name() {
+	case "Binary":
+		op, err := f.operator()
+		if err != nil {
+			return nil, err
+		}
+		left, err := f.expr("left")
+		if err != nil {
+			return nil, err
+		}
+		right, err := f.expr("right")
+		return NewBinary(left, op, right), err
+	case "Unary":
+		op, err := f.operator()
+		if err != nil {
+			return nil, err
+		}
+		right, err := f.expr("right")
+		return NewUnary(op, right), err
+	case "Literal":
+		return decodeLiteral(f)
+	case "Grouping":
+		inner, err := f.expr("inner")
+		return NewGrouping(inner), err
+	case "IfExpr":
+		return decodeIf(f)
+	case "Assignment":
+		var name string
+		err := f.get("name", &name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := f.expr("value")
+		if err != nil {
+			return nil, err
+		}
+		p, err := f.pos()
+		return NewAssignment(name, value, p), err
+	case "FnExpr":
+		return decodeFn(f)
+	case "Call":
+		callee, err := f.expr("callee")
+		if err != nil {
+			return nil, err
+		}
+		var raws []json.RawMessage
+		err = f.get("args", &raws)
+		if err != nil {
+			return nil, err
+		}
+		args := []Expr{}
+		for _, raw := range raws {
+			arg, err := decodeExpr(raw)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return NewCall(callee, args), nil
+	case "Get":
+		object, err := f.expr("object")
+		if err != nil {
+			return nil, err
+		}
+		var name string
+		err = f.get("name", &name)
+		if err != nil {
+			return nil, err
+		}
+		p, err := f.pos()
+		return NewGet(object, name, p), err
+	case "Instantiate":
+		fn, err := f.expr("fn")
+		if err != nil {
+			return nil, err
+		}
+		var raws []json.RawMessage
+		err = f.get("type_args", &raws)
+		if err != nil {
+			return nil, err
+		}
+		args := []ValueKind{}
+		for _, raw := range raws {
+			arg, err := decodeKind(raw)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		p, err := f.pos()
+		return NewInstantiate(fn, args, p), err
+	}
+	return nil, fmt.Errorf("unknown expression %q", f.name())
+}
+
+func decodeLiteral(f fields) (Expr, error) {
+	var kind, value string
+	err := f.get("kind", &kind)
+	if err != nil {
+		return nil, err
+	}
+	err = f.get("value", &value)
+	if err != nil {
+		return nil, err
+	}
+	p, err := f.pos()
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "int":
+		return NewLiteral(value, token.INT, p), nil
+	case "float":
+		return NewLiteral(value, token.FLOAT, p), nil
+	case "nil":
+		return NewLiteral(value, token.NIL, p), nil
+	case "ident":
+		return NewLiteral(value, token.IDENT, p), nil
+	case "string":
+		return NewLiteral("\""+value+"\"", token.STRING, p), nil
+	}
+	return nil, fmt.Errorf("unknown literal kind %q", kind)
+}
+
+func decodeIf(f fields) (IfExpr, error) {
+	cond, err := f.expr("cond")
+	if err != nil {
+		return IfExpr{}, err
+	}
+	body, err := f.stmts("body")
+	if err != nil {
+		return IfExpr{}, err
+	}
+	var raws []fields
+	err = f.get("else_ifs", &raws)
+	if err != nil {
+		return IfExpr{}, err
+	}
+	var else_ifs []IfExpr
+	for _, raw := range raws {
+		elif, err := decodeIf(raw)
+		if err != nil {
+			return IfExpr{}, err
+		}
+		else_ifs = append(else_ifs, elif)
+	}
+	el, err := f.stmts("else")
+	if err != nil {
+		return IfExpr{}, err
+	}
+	p, err := f.pos()
+	if err != nil {
+		return IfExpr{}, err
+	}
+	end, err := f.posAt("end")
+	i := NewIfExpr(cond, body, else_ifs, el, p)
+	i.End = end
+	return i, err
+}
+
+func decodeFn(f fields) (Expr, error) {
+	typeParams, err := f.typeParams()
+	if err != nil {
+		return nil, err
+	}
+	params, err := f.params()
+	if err != nil {
+		return nil, err
+	}
+	rtype, err := f.kind("return")
+	if err != nil {
+		return nil, err
+	}
+	body, err := f.stmts("body")
+	if err != nil {
+		return nil, err
+	}
+	var captures []struct {
+		Name  string
+		ByRef bool `json:"by_ref"`
+	}
+	err = f.get("captures", &captures)
+	if err != nil {
+		return nil, err
+	}
+	p, err := f.pos()
+	if err != nil {
+		return nil, err
+	}
+	end, err := f.posAt("end")
+	if err != nil {
+		return nil, err
+	}
+
+	fn := NewFn(typeParams, params, body, rtype, p)
+	fn.End = end
+	for _, c := range captures {
+		fn.Captures = append(fn.Captures, Upvalue{Name: c.Name, ByRef: c.ByRef})
+	}
+	return fn, nil
+}
+
+func decodeKind(raw json.RawMessage) (ValueKind, error) {
+	var f fields
+	err := json.Unmarshal(raw, &f)
+	if err != nil || f == nil {
+		return nil, err
+	}
+
+	var kind string
+	err = f.get("kind", &kind)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "int":
+		return INT, nil
+	case "float":
+		return FLOAT, nil
+	case "string":
+		return STRING, nil
+	case "nil":
+		return NIL, nil
+	case "var":
+		var name string
+		err := f.get("name", &name)
+		return TypeVar{Name: name}, err
+	case "fn":
+		typeParams, err := f.typeParams()
+		if err != nil {
+			return nil, err
+		}
+		params, err := f.params()
+		if err != nil {
+			return nil, err
+		}
+		rtype, err := f.kind("return")
+		return NewFnT(typeParams, params, rtype), err
+	}
+	return nil, fmt.Errorf("unknown type %q", kind)
+}
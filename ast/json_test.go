@@ -0,0 +1,106 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/parser"
+)
+
+func parse(t *testing.T, name string, src string) ast.File {
+	t.Helper()
+	f, err := parser.ParseSource(context.Background(), name, src)
+	if err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	return f
+}
+
+func roundTrip(t *testing.T, f ast.File) ast.File {
+	t.Helper()
+	buf, err := ast.MarshalJSON(f)
+	if err != nil {
+		t.Fatalf("%s: %s", f.Name, err)
+	}
+	got, err := ast.UnmarshalJSON(buf)
+	if err != nil {
+		t.Fatalf("%s: %s", f.Name, err)
+	}
+	return got
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*.gr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		// the files with syntax errors have no tree
+		if _, err := os.Stat(strings.TrimSuffix(file, ".gr") + ".diag"); err == nil {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := parse(t, filepath.Base(file), string(src))
+		if got := roundTrip(t, f); !reflect.DeepEqual(got, f) {
+			t.Errorf("%s: read back\n%s\nwant\n%s", file, got.Stmts, f.Stmts)
+		}
+	}
+}
+
+func TestJSONKinds(t *testing.T) {
+	f := parse(t, "kinds.gr", "fn max[T: numeric](a: T, b: T): T if a > b; a else b end end\nlet f: fn(int, string): fn(): float = nil\n")
+	got := roundTrip(t, f)
+	if !reflect.DeepEqual(got, f) {
+		t.Fatalf("read back\n%s\nwant\n%s", got.Stmts, f.Stmts)
+	}
+	max := got.Stmts[0].(ast.VarDecl).Kind.(ast.Fn)
+	if len(max.TypeParams) != 1 || max.TypeParams[0].Constraint != ast.NUMERIC {
+		t.Errorf("max has type params %v", max.TypeParams)
+	}
+	if _, ok := max.Rtype.(ast.TypeVar); !ok {
+		t.Errorf("max returns %#v, want a type variable", max.Rtype)
+	}
+	f2 := got.Stmts[1].(ast.VarDecl).Kind.(ast.Fn)
+	if _, ok := f2.Rtype.(ast.Fn); !ok || len(f2.Params) != 2 || f2.Params[1].Kind != ast.STRING {
+		t.Errorf("f has kind %s", f2)
+	}
+}
+
+func TestJSONVersion(t *testing.T) {
+	buf, err := ast.MarshalJSON(parse(t, "version.gr", "1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := fmt.Sprintf(`"version":%d`, ast.JSONVersion)
+	for _, data := range []string{
+		strings.Replace(string(buf), version, `"version":99`, 1),
+		strings.Replace(string(buf), version, `"versions":2`, 1),
+	} {
+		if data == string(buf) {
+			t.Fatalf("no version in %s", buf)
+		}
+		if _, err := ast.UnmarshalJSON([]byte(data)); err == nil || !strings.Contains(err.Error(), "version") {
+			t.Errorf("decoded %s: %v", data, err)
+		}
+	}
+}
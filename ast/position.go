@@ -0,0 +1,108 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Positions hold rune columns. Tools see the source in other units, the
+// JSON output counts bytes and the language server protocol counts UTF-16
+// code units with lines and characters from 0
+
+// ByteCol returns the byte column of p in f, counting from 1. The zero
+// column of a node without a position stays zero
+func (f File) ByteCol(p Pos) int {
+	if p.Col < 1 {
+		return p.Col
+	}
+	line := f.line(p.Line)
+	n := 0
+	for col := 1; col < p.Col; col++ {
+		if line == "" {
+			n++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+		n += size
+	}
+	return n + 1
+}
+
+// PosFromByteCol returns the position of the byte column col on line
+func (f File) PosFromByteCol(line, col int) Pos {
+	if col < 1 {
+		return Pos{Line: line, Col: col}
+	}
+	s := f.line(line)
+	n := 1
+	for i := 1; i < col; n++ {
+		if s == "" {
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		i += size
+	}
+	return Pos{Line: line, Col: n}
+}
+
+// LSPPosition returns p as a language server position
+func (f File) LSPPosition(p Pos) (line, character int) {
+	s := f.line(p.Line)
+	for col := 1; col < p.Col; col++ {
+		if s == "" {
+			character++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		character += utf16Len(r)
+	}
+	return p.Line - 1, character
+}
+
+// PosFromLSP returns the position of a language server position
+func (f File) PosFromLSP(line, character int) Pos {
+	s := f.line(line + 1)
+	col := 1
+	for i := 0; i < character; col++ {
+		if s == "" {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		i += utf16Len(r)
+	}
+	return Pos{Line: line + 1, Col: col}
+}
+
+// line returns the text of line n without its newline, a line outside
+// the file is empty
+func (f File) line(n int) string {
+	if n < 1 || n > len(f.Lines) {
+		return ""
+	}
+	return strings.TrimSuffix(f.Lines[n-1], "\n")
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
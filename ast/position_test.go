@@ -0,0 +1,47 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast
+
+import "testing"
+
+func TestPositions(t *testing.T) {
+	f := File{Lines: []string{"let s = \"é𝄞x\"\n", "\tx\n"}}
+	tests := []struct {
+		pos     Pos
+		byteCol int
+		lspChar int
+	}{
+		{Pos{1, 1}, 1, 0},
+		{Pos{1, 10}, 10, 9},  // é
+		{Pos{1, 11}, 12, 10}, // 𝄞 is 4 bytes and 2 UTF-16 units
+		{Pos{1, 12}, 16, 12}, // x
+		{Pos{1, 14}, 18, 14}, // past the end of the line
+		{Pos{2, 2}, 2, 1},
+		{Pos{3, 2}, 2, 1}, // past the end of the file
+	}
+	for _, tt := range tests {
+		if got := f.ByteCol(tt.pos); got != tt.byteCol {
+			t.Errorf("ByteCol(%v) = %d, want %d", tt.pos, got, tt.byteCol)
+		}
+		if got := f.PosFromByteCol(tt.pos.Line, tt.byteCol); got != tt.pos {
+			t.Errorf("PosFromByteCol(%d, %d) = %v, want %v", tt.pos.Line, tt.byteCol, got, tt.pos)
+		}
+		line, char := f.LSPPosition(tt.pos)
+		if line != tt.pos.Line-1 || char != tt.lspChar {
+			t.Errorf("LSPPosition(%v) = %d, %d, want %d, %d", tt.pos, line, char, tt.pos.Line-1, tt.lspChar)
+		}
+		if got := f.PosFromLSP(line, char); got != tt.pos {
+			t.Errorf("PosFromLSP(%d, %d) = %v, want %v", line, char, got, tt.pos)
+		}
+	}
+}
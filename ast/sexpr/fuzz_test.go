@@ -0,0 +1,58 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package sexpr
+
+import (
+	"testing"
+	"time"
+)
+
+// FuzzRead checks that reading never panics or hangs and that a tree that
+// was read is read back the same from its s-expression
+func FuzzRead(f *testing.F) {
+	for _, src := range []string{
+		`(import "math/ops")`,
+		"(pub let max (fn [T: comparable] T (T T)) (fn [T: comparable] T (a: T b: T) ((if (> a b) (a) (b)))))",
+		`(let mut s string "say \"hi\"\n")`,
+		"(while (< (call group 1) 3) ((= s (+ s \"!\"))))",
+		"(get (call (instantiate max int) (- 1) (~ 2)) y)",
+		"(let x (fn int (a",
+	} {
+		f.Add(src)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			stmts, err := Read(src)
+			if err != nil {
+				_ = err.Error()
+				return
+			}
+			for _, stmt := range stmts {
+				read, err := Read(stmt.String())
+				if err != nil {
+					t.Errorf("cannot read %s: %v", stmt, err)
+				} else if len(read) != 1 || read[0].String() != stmt.String() {
+					t.Errorf("read %s as %s", stmt, read)
+				}
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Read did not finish")
+		}
+	})
+}
@@ -0,0 +1,643 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package sexpr reads the s-expressions printed by the String methods of
+// the ast back into trees. Positions are not part of the text, so every
+// position in a tree read by this package is zero
+package sexpr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/token"
+)
+
+// Read reads a sequence of statements, such as the output of graphene parse
+func Read(src string) (ast.Stmts, error) {
+	r, err := newReader(src)
+	if err != nil {
+		return nil, err
+	}
+	stmts := ast.Stmts{}
+	for !r.atEnd() {
+		stmt, err := r.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// ReadExpr reads a single expression
+func ReadExpr(src string) (ast.Expr, error) {
+	r, err := newReader(src)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	if !r.atEnd() {
+		return nil, r.errorf("unexpected %s after expression", r.peek())
+	}
+	return expr, nil
+}
+
+type tokKind uint8
+
+const (
+	atom tokKind = iota
+	str
+	lparen
+	rparen
+	lbracket
+	rbracket
+	colon
+)
+
+type tok struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t tok) String() string {
+	if t.kind == str {
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type reader struct {
+	toks []tok
+	pos  int
+	// offset of the end of the source, for errors at EOF
+	end int
+}
+
+// ReadErr is an error at a byte offset into the text being read
+type ReadErr struct {
+	Offset int
+	msg    string
+}
+
+func (e ReadErr) Error() string {
+	return fmt.Sprintf("sexpr: %d: %s", e.Offset, e.msg)
+}
+
+func newReader(src string) (*reader, error) {
+	r := &reader{end: len(src)}
+	delims := map[rune]tokKind{'(': lparen, ')': rparen, '[': lbracket, ']': rbracket, ':': colon}
+	runes := []rune(src)
+	offset := 0
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		start := offset
+		offset += len(string(c))
+		if unicode.IsSpace(c) {
+			continue
+		}
+		if kind, ok := delims[c]; ok {
+			r.toks = append(r.toks, tok{kind, string(c), start})
+			continue
+		}
+		if c == '"' {
+			var val strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				c = runes[i]
+				offset += len(string(c))
+				if c == '"' {
+					closed = true
+					break
+				}
+				if c == '\\' && i+1 < len(runes) {
+					i++
+					offset += len(string(runes[i]))
+					esc, ok := map[rune]rune{'"': '"', '\\': '\\', 'n': '\n', 't': '\t', 'r': '\r', 'v': '\v'}[runes[i]]
+					if !ok {
+						return nil, ReadErr{offset, fmt.Sprintf("invalid escape \\%c", runes[i])}
+					}
+					c = esc
+				}
+				val.WriteRune(c)
+			}
+			if !closed {
+				return nil, ReadErr{start, "unclosed string"}
+			}
+			r.toks = append(r.toks, tok{str, val.String(), start})
+			continue
+		}
+
+		text := string(c)
+		for i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != '"' {
+			if _, ok := delims[runes[i+1]]; ok {
+				break
+			}
+			i++
+			offset += len(string(runes[i]))
+			text += string(runes[i])
+		}
+		r.toks = append(r.toks, tok{atom, text, start})
+	}
+	return r, nil
+}
+
+func (r *reader) atEnd() bool {
+	return r.pos >= len(r.toks)
+}
+
+func (r *reader) peek() tok {
+	if r.atEnd() {
+		return tok{kind: atom, text: "EOF", pos: r.end}
+	}
+	return r.toks[r.pos]
+}
+
+// head is the atom after the "(" at the current position, if there is one
+func (r *reader) head() string {
+	if r.peek().kind != lparen || r.pos+1 >= len(r.toks) || r.toks[r.pos+1].kind != atom {
+		return ""
+	}
+	return r.toks[r.pos+1].text
+}
+
+func (r *reader) next() tok {
+	t := r.peek()
+	if !r.atEnd() {
+		r.pos++
+	}
+	return t
+}
+
+func (r *reader) errorf(format string, args ...any) error {
+	return ReadErr{r.peek().pos, fmt.Sprintf(format, args...)}
+}
+
+func (r *reader) expect(kind tokKind, text string) error {
+	t := r.peek()
+	if t.kind != kind || (kind == atom && t.text != text) {
+		return r.errorf("expected %q got %s", text, t)
+	}
+	r.next()
+	return nil
+}
+
+func (r *reader) check(kind tokKind) bool {
+	return !r.atEnd() && r.peek().kind == kind
+}
+
+func (r *reader) name() (string, error) {
+	t := r.peek()
+	if t.kind != atom || !isIdent(t.text) {
+		return "", r.errorf("expected identifier got %s", t)
+	}
+	r.next()
+	return t.text, nil
+}
+
+func isIdent(s string) bool {
+	for i, c := range s {
+		if !(unicode.IsLetter(c) || c == '_' || (i > 0 && unicode.IsDigit(c))) {
+			return false
+		}
+	}
+	return s != "" && !keywords[s]
+}
+
+var keywords = map[string]bool{
+	"int": true, "float": true, "string": true, "nil": true, "let": true,
+	"mut": true, "pub": true, "if": true, "else": true, "end": true,
+	"while": true, "fn": true, "return": true, "import": true,
+}
+
+// conversions are the type keywords that name a builtin when called
+var conversions = map[string]bool{"int": true, "float": true, "string": true}
+
+// operators maps the spelling of every operator to its token kind
+var operators = make(map[string]token.TokenKind)
+
+var unary = map[token.TokenKind]bool{token.MINUS: true, token.BANG: true, token.TILDE: true}
+
+func init() {
+	for _, kind := range []token.TokenKind{
+		token.PLUS, token.MINUS, token.STAR, token.SLASH, token.PERCENT,
+		token.AMP, token.PIPE, token.CARET, token.TILDE, token.SHL, token.SHR,
+		token.EQEQ, token.NEQ, token.LESS, token.LESSEQ, token.GREATER,
+		token.GREATEREQ, token.BANG,
+	} {
+		operators[kind.String()] = kind
+	}
+}
+
+func (r *reader) stmt() (ast.Stmt, error) {
+	switch r.head() {
+	case "let":
+		r.pos += 2
+		return r.varDecl()
+	case "while":
+		r.pos += 2
+		cond, err := r.expr()
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.block()
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewWhileStmt(cond, body, ast.Pos{}), r.expect(rparen, ")")
+	case "return":
+		r.pos += 2
+		value, err := r.expr()
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewReturn(value, ast.Pos{}), r.expect(rparen, ")")
+	case "import":
+		r.pos += 2
+		if !r.check(str) {
+			return nil, r.errorf("expected string got %s", r.peek())
+		}
+		path := r.next().text
+		return ast.NewImport(path, ast.Pos{}), r.expect(rparen, ")")
+	}
+
+	expr, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewExprStmt(expr), nil
+}
+
+func (r *reader) varDecl() (ast.Stmt, error) {
+	pub := r.peek().text == "pub" && r.check(atom)
+	if pub {
+		r.next()
+	}
+	mut := r.peek().text == "mut" && r.check(atom)
+	if mut {
+		r.next()
+	}
+	name, err := r.name()
+	if err != nil {
+		return nil, err
+	}
+	var kind ast.ValueKind
+	if r.check(atom) && r.peek().text == "_" {
+		r.next()
+	} else {
+		kind, err = r.kind()
+		if err != nil {
+			return nil, err
+		}
+	}
+	value, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	v := ast.NewVarDecl(name, kind, value, mut, ast.Pos{})
+	if pub {
+		v = v.Export()
+	}
+	return v, r.expect(rparen, ")")
+}
+
+// block reads a parenthesised list of statements, an empty block is nil
+// like the blocks of the parser
+func (r *reader) block() ([]ast.Stmt, error) {
+	err := r.expect(lparen, "(")
+	if err != nil {
+		return nil, err
+	}
+	var stmts []ast.Stmt
+	for !r.check(rparen) {
+		if r.atEnd() {
+			return nil, r.errorf("expected \")\" got EOF")
+		}
+		stmt, err := r.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	r.next()
+	return stmts, nil
+}
+
+func (r *reader) expr() (ast.Expr, error) {
+	t := r.peek()
+	switch t.kind {
+	case str:
+		r.next()
+		return ast.NewLiteral("\""+t.text+"\"", token.STRING, ast.Pos{}), nil
+	case atom:
+		r.next()
+		return literal(t)
+	case lparen:
+	default:
+		return nil, r.errorf("expected expression got %s", t)
+	}
+
+	head := r.head()
+	if head == "" {
+		return nil, r.errorf("expected an operator or keyword after \"(\"")
+	}
+	r.pos += 2
+
+	var expr ast.Expr
+	var err error
+	switch head {
+	case "group":
+		var inner ast.Expr
+		inner, err = r.expr()
+		expr = ast.NewGrouping(inner)
+	case "call":
+		expr, err = r.call()
+	case "=":
+		expr, err = r.assignment()
+	case ".":
+		expr, err = r.get()
+	case "instantiate":
+		expr, err = r.instantiate()
+	case "if":
+		return r.ifExpr()
+	case "fn":
+		return r.fn()
+	default:
+		op, ok := operators[head]
+		if !ok {
+			return nil, ReadErr{t.pos, fmt.Sprintf("unknown form %q", head)}
+		}
+		expr, err = r.operator(op)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return expr, r.expect(rparen, ")")
+}
+
+func literal(t tok) (ast.Expr, error) {
+	switch {
+	case t.text == "nil":
+		return ast.NewLiteral(t.text, token.NIL, ast.Pos{}), nil
+	case unicode.IsDigit([]rune(t.text)[0]):
+		for _, c := range t.text {
+			if !unicode.IsDigit(c) && c != '.' {
+				return nil, ReadErr{t.pos, fmt.Sprintf("invalid number %q", t.text)}
+			}
+		}
+		if strings.Contains(t.text, ".") {
+			return ast.NewLiteral(t.text, token.FLOAT, ast.Pos{}), nil
+		}
+		return ast.NewLiteral(t.text, token.INT, ast.Pos{}), nil
+	case isIdent(t.text), conversions[t.text]:
+		return ast.NewLiteral(t.text, token.IDENT, ast.Pos{}), nil
+	}
+	return nil, ReadErr{t.pos, fmt.Sprintf("unexpected %s", t)}
+}
+
+func (r *reader) operator(op token.TokenKind) (ast.Expr, error) {
+	operator := token.Token{Kind: op, Literal: op.String()}
+	left, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	if r.check(rparen) {
+		if !unary[op] {
+			return nil, r.errorf("%s is not a unary operator", op)
+		}
+		return ast.NewUnary(operator, left), nil
+	}
+	right, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewBinary(left, operator, right), nil
+}
+
+func (r *reader) call() (ast.Expr, error) {
+	callee, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	args := []ast.Expr{}
+	for !r.check(rparen) && !r.atEnd() {
+		arg, err := r.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return ast.NewCall(callee, args), nil
+}
+
+func (r *reader) assignment() (ast.Expr, error) {
+	name, err := r.name()
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewAssignment(name, value, ast.Pos{}), nil
+}
+
+func (r *reader) get() (ast.Expr, error) {
+	object, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	name, err := r.name()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewGet(object, name, ast.Pos{}), nil
+}
+
+func (r *reader) instantiate() (ast.Expr, error) {
+	fn, err := r.expr()
+	if err != nil {
+		return nil, err
+	}
+	args := []ast.ValueKind{}
+	for !r.check(rparen) && !r.atEnd() {
+		arg, err := r.kind()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return ast.NewInstantiate(fn, args, ast.Pos{}), nil
+}
+
+// ifExpr reads the rest of (if cond (body) (if cond (body))... (else)),
+// else ifs are told apart from the else block by their if
+func (r *reader) ifExpr() (ast.IfExpr, error) {
+	cond, err := r.expr()
+	if err != nil {
+		return ast.IfExpr{}, err
+	}
+	body, err := r.block()
+	if err != nil {
+		return ast.IfExpr{}, err
+	}
+
+	var else_ifs []ast.IfExpr
+	for r.head() == "if" {
+		r.pos += 2
+		elif, err := r.ifExpr()
+		if err != nil {
+			return ast.IfExpr{}, err
+		}
+		else_ifs = append(else_ifs, elif)
+	}
+	var el []ast.Stmt
+	if r.check(lparen) {
+		el, err = r.block()
+		if err != nil {
+			return ast.IfExpr{}, err
+		}
+		if el == nil {
+			el = []ast.Stmt{}
+		}
+	}
+
+	return ast.NewIfExpr(cond, body, else_ifs, el, ast.Pos{}), r.expect(rparen, ")")
+}
+
+func (r *reader) fn() (ast.Expr, error) {
+	typeParams, err := r.typeParams()
+	if err != nil {
+		return nil, err
+	}
+	rtype, err := r.kind()
+	if err != nil {
+		return nil, err
+	}
+	err = r.expect(lparen, "(")
+	if err != nil {
+		return nil, err
+	}
+	params := []ast.Param{}
+	for !r.check(rparen) && !r.atEnd() {
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		err = r.expect(colon, ":")
+		if err != nil {
+			return nil, err
+		}
+		kind, err := r.kind()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, ast.NewParam(name, kind))
+	}
+	r.next()
+	body, err := r.block()
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewFn(typeParams, params, body, rtype, ast.Pos{}), r.expect(rparen, ")")
+}
+
+func (r *reader) typeParams() ([]ast.TypeParam, error) {
+	if !r.check(lbracket) {
+		return nil, nil
+	}
+	r.next()
+	params := []ast.TypeParam{}
+	for !r.check(rbracket) {
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		err = r.expect(colon, ":")
+		if err != nil {
+			return nil, err
+		}
+		param := ast.NewTypeParam(name, ast.ANY)
+		switch c := r.next(); c.text {
+		case "any":
+		case "comparable":
+			param.Constraint = ast.COMPARABLE
+		case "numeric":
+			param.Constraint = ast.NUMERIC
+		default:
+			return nil, ReadErr{c.pos, fmt.Sprintf("unknown constraint %s", c)}
+		}
+		params = append(params, param)
+	}
+	r.next()
+	return params, nil
+}
+
+func (r *reader) kind() (ast.ValueKind, error) {
+	t := r.peek()
+	if r.atEnd() {
+		return nil, r.errorf("expected type got %s", t)
+	}
+	if t.kind == atom {
+		r.next()
+		switch t.text {
+		case "int":
+			return ast.INT, nil
+		case "float":
+			return ast.FLOAT, nil
+		case "string":
+			return ast.STRING, nil
+		case "nil":
+			return ast.NIL, nil
+		}
+		if isIdent(t.text) {
+			return ast.TypeVar{Name: t.text}, nil
+		}
+		return nil, ReadErr{t.pos, fmt.Sprintf("expected type got %s", t)}
+	}
+	if r.head() != "fn" {
+		return nil, r.errorf("expected type got %s", t)
+	}
+	r.pos += 2
+
+	typeParams, err := r.typeParams()
+	if err != nil {
+		return nil, err
+	}
+	rtype, err := r.kind()
+	if err != nil {
+		return nil, err
+	}
+	err = r.expect(lparen, "(")
+	if err != nil {
+		return nil, err
+	}
+	params := []ast.Param{}
+	for !r.check(rparen) && !r.atEnd() {
+		kind, err := r.kind()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, ast.NewParam("", kind))
+	}
+	r.next()
+
+	return ast.NewFnT(typeParams, params, rtype), r.expect(rparen, ")")
+}
@@ -0,0 +1,288 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package sexpr
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/parser"
+	"zimlit/graphene/token"
+)
+
+// gen generates random trees shaped like the trees of the parser, with zero
+// positions and nil blocks for empty bodies
+type gen struct {
+	r *rand.Rand
+}
+
+var (
+	names     = []string{"x", "y1", "_t", "group", "call", "instantiate", "café"}
+	binaryOps = []token.TokenKind{
+		token.PLUS, token.MINUS, token.STAR, token.SLASH, token.PERCENT,
+		token.AMP, token.PIPE, token.CARET, token.SHL, token.SHR, token.EQEQ,
+		token.NEQ, token.LESS, token.LESSEQ, token.GREATER, token.GREATEREQ,
+	}
+	unaryOps    = []token.TokenKind{token.MINUS, token.BANG, token.TILDE}
+	stringParts = []string{"a", "\"", "\\", "\n", "\t", "\r", "\v", "é", " ", "(", ")", "_"}
+)
+
+func (g gen) name() string {
+	return names[g.r.Intn(len(names))]
+}
+
+func (g gen) op(ops []token.TokenKind) token.Token {
+	kind := ops[g.r.Intn(len(ops))]
+	return token.Token{Kind: kind, Literal: kind.String()}
+}
+
+func (g gen) literal() ast.Expr {
+	switch g.r.Intn(5) {
+	case 0:
+		return ast.NewLiteral("42", token.INT, ast.Pos{})
+	case 1:
+		return ast.NewLiteral("1.5", token.FLOAT, ast.Pos{})
+	case 2:
+		return ast.NewLiteral("nil", token.NIL, ast.Pos{})
+	case 3:
+		var str strings.Builder
+		for i := g.r.Intn(4); i > 0; i-- {
+			str.WriteString(stringParts[g.r.Intn(len(stringParts))])
+		}
+		return ast.NewLiteral("\""+str.String()+"\"", token.STRING, ast.Pos{})
+	}
+	return ast.NewLiteral(g.name(), token.IDENT, ast.Pos{})
+}
+
+func (g gen) expr(depth int) ast.Expr {
+	if depth == 0 {
+		return g.literal()
+	}
+	depth--
+
+	switch g.r.Intn(11) {
+	case 0:
+		return ast.NewBinary(g.expr(depth), g.op(binaryOps), g.expr(depth))
+	case 1:
+		return ast.NewUnary(g.op(unaryOps), g.expr(depth))
+	case 2:
+		return ast.NewGrouping(g.expr(depth))
+	case 3:
+		args := []ast.Expr{}
+		for i := g.r.Intn(3); i > 0; i-- {
+			args = append(args, g.expr(depth))
+		}
+		return ast.NewCall(g.expr(depth), args)
+	case 4:
+		return ast.NewGet(g.expr(depth), g.name(), ast.Pos{})
+	case 5:
+		args := []ast.ValueKind{g.kind(depth)}
+		return ast.NewInstantiate(g.expr(depth), args, ast.Pos{})
+	case 6:
+		return ast.NewAssignment(g.name(), g.expr(depth), ast.Pos{})
+	case 7:
+		return g.ifExpr(depth)
+	case 8:
+		params := []ast.Param{}
+		for i := g.r.Intn(3); i > 0; i-- {
+			params = append(params, ast.NewParam(g.name(), g.kind(depth)))
+		}
+		return ast.NewFn(g.typeParams(), params, g.block(depth), g.kind(depth), ast.Pos{})
+	}
+	return g.literal()
+}
+
+func (g gen) ifExpr(depth int) ast.IfExpr {
+	var else_ifs []ast.IfExpr
+	for i := g.r.Intn(3); i > 0; i-- {
+		else_ifs = append(else_ifs, ast.NewIfExpr(g.expr(depth), g.block(depth), nil, nil, ast.Pos{}))
+	}
+	var el []ast.Stmt
+	if g.r.Intn(2) == 0 {
+		el = g.block(depth)
+		if el == nil {
+			el = []ast.Stmt{}
+		}
+	}
+	return ast.NewIfExpr(g.expr(depth), g.block(depth), else_ifs, el, ast.Pos{})
+}
+
+func (g gen) typeParams() []ast.TypeParam {
+	if g.r.Intn(2) == 0 {
+		return nil
+	}
+	params := []ast.TypeParam{}
+	for i := g.r.Intn(3) + 1; i > 0; i-- {
+		params = append(params, ast.NewTypeParam(g.name(), ast.Constraint(g.r.Intn(3))))
+	}
+	return params
+}
+
+func (g gen) kind(depth int) ast.ValueKind {
+	switch g.r.Intn(5) {
+	case 0:
+		return ast.INT
+	case 1:
+		return ast.FLOAT
+	case 2:
+		return ast.STRING
+	case 3:
+		return ast.TypeVar{Name: g.name()}
+	}
+	if depth == 0 {
+		return ast.INT
+	}
+	params := []ast.Param{}
+	for i := g.r.Intn(3); i > 0; i-- {
+		params = append(params, ast.NewParam("", g.kind(depth-1)))
+	}
+	return ast.NewFnT(g.typeParams(), params, g.kind(depth-1))
+}
+
+func (g gen) block(depth int) []ast.Stmt {
+	var stmts []ast.Stmt
+	for i := g.r.Intn(3); i > 0; i-- {
+		stmts = append(stmts, g.stmt(depth))
+	}
+	return stmts
+}
+
+func (g gen) stmt(depth int) ast.Stmt {
+	switch g.r.Intn(6) {
+	case 0:
+		var kind ast.ValueKind
+		if g.r.Intn(2) == 0 {
+			kind = g.kind(depth)
+		}
+		v := ast.NewVarDecl(g.name(), kind, g.expr(depth), g.r.Intn(2) == 0, ast.Pos{})
+		if g.r.Intn(2) == 0 {
+			v = v.Export()
+		}
+		return v
+	case 1:
+		return ast.NewWhileStmt(g.expr(depth), g.block(depth), ast.Pos{})
+	case 2:
+		return ast.NewReturn(g.expr(depth), ast.Pos{})
+	case 3:
+		return ast.NewImport(stringParts[g.r.Intn(len(stringParts))]+"/m", ast.Pos{})
+	}
+	return ast.NewExprStmt(g.expr(depth))
+}
+
+func TestReadExprRoundTrip(t *testing.T) {
+	property := func(seed int64) bool {
+		g := gen{rand.New(rand.NewSource(seed))}
+		want := g.expr(4)
+		got, err := ReadExpr(want.String())
+		if err != nil {
+			t.Logf("%s: %v", want, err)
+			return false
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("read %s as %s", want, got)
+			return false
+		}
+		return true
+	}
+
+	err := quick.Check(property, &quick.Config{MaxCount: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadRoundTrip(t *testing.T) {
+	property := func(seed int64) bool {
+		g := gen{rand.New(rand.NewSource(seed))}
+		want := ast.Stmts{}
+		var src strings.Builder
+		for i := g.r.Intn(4); i > 0; i-- {
+			stmt := g.stmt(3)
+			want = append(want, stmt)
+			src.WriteString(stmt.String() + "\n")
+		}
+		got, err := Read(src.String())
+		if err != nil {
+			t.Logf("%s: %v", src.String(), err)
+			return false
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("read %s as %s", src.String(), got)
+			return false
+		}
+		return true
+	}
+
+	err := quick.Check(property, &quick.Config{MaxCount: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadParsed(t *testing.T) {
+	src := `import "math/ops"
+pub fn max[T: comparable](a: T, b: T): T
+	if a > b; a else b end
+end
+let mut s = "say \"hi\"\n"
+let group = fn (x: int): int (x + 1) end
+while group(1) < 3
+	s += "!"
+end
+if 1 2 else if 0 3 else end
+max[int](-1, ~2).y
+`
+	f, err := parser.ParseSource(context.Background(), "test.gr", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range f.Stmts {
+		got, err := Read(want.String())
+		if err != nil {
+			t.Fatalf("%s: %v", want, err)
+		}
+		if len(got) != 1 || got[0].String() != want.String() {
+			t.Errorf("read %s as %s", want, got)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, src := range []string{
+		"(",
+		"(+ 1",
+		"(call)",
+		"(! 1 2 3)",
+		"(foo 1)",
+		"\"abc",
+		"\"\\q\"",
+		"(let x _)",
+		"(fn int (x int) ())",
+		"(if 1 (2) (3) (4))",
+		"1x",
+		")",
+		"(let x (fn int (a",
+		"(let x (fn int (int",
+		"(let f (fn int (a: int",
+		"(let x",
+	} {
+		_, err := Read(src)
+		if err == nil {
+			t.Errorf("Read(%q) succeeded", src)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast
+
+import "zimlit/graphene/token"
+
+// ShiftLines returns a copy of stmt with every position moved down by n
+// lines, used to reuse statements after lines were added or removed above
+// them
+func ShiftLines(stmt Stmt, n int) Stmt {
+	return stmt.Accept(mapper(func(p Pos) Pos {
+		p.Line += n
+		return p
+	})).(Stmt)
+}
+
+// mapper copies nodes with every position passed through it
+type mapper func(Pos) Pos
+
+func (s mapper) pos(p Pos) Pos {
+	return s(p)
+}
+
+// end maps the position of an end, which is zero when it is not known
+func (s mapper) end(p Pos) Pos {
+	if p.Line == 0 {
+		return p
+	}
+	return s(p)
+}
+
+func (s mapper) token(t token.Token) token.Token {
+	p := s(Pos{Line: t.Line, Col: t.Col})
+	t.Line, t.Col = p.Line, p.Col
+	return t
+}
+
+func (s mapper) expr(e Expr) Expr {
+	if e == nil {
+		return nil
+	}
+	return e.Accept(s).(Expr)
+}
+
+func (s mapper) exprs(es []Expr) []Expr {
+	if es == nil {
+		return nil
+	}
+	shifted := make([]Expr, len(es))
+	for i, e := range es {
+		shifted[i] = s.expr(e)
+	}
+	return shifted
+}
+
+func (s mapper) stmts(stmts []Stmt) []Stmt {
+	if stmts == nil {
+		return nil
+	}
+	shifted := make([]Stmt, len(stmts))
+	for i, stmt := range stmts {
+		shifted[i] = stmt.Accept(s).(Stmt)
+	}
+	return shifted
+}
+
+func (s mapper) VisitBinary(b Binary) any {
+	b.Left, b.Right = s.expr(b.Left), s.expr(b.Right)
+	b.Operator = s.token(b.Operator)
+	return b
+}
+
+func (s mapper) VisitUnary(u Unary) any {
+	u.Right = s.expr(u.Right)
+	u.Operator = s.token(u.Operator)
+	return u
+}
+
+func (s mapper) VisitLiteral(l Literal) any {
+	l.Pos = s.pos(l.Pos)
+	return l
+}
+
+func (s mapper) VisitGrouping(g Grouping) any {
+	g.Inner = s.expr(g.Inner)
+	return g
+}
+
+func (s mapper) VisitIfExpr(i IfExpr) any {
+	i.Condition = s.expr(i.Condition)
+	i.Body, i.Else = s.stmts(i.Body), s.stmts(i.Else)
+	if i.Else_ifs != nil {
+		elifs := make([]IfExpr, len(i.Else_ifs))
+		for j, e := range i.Else_ifs {
+			elifs[j] = s.VisitIfExpr(e).(IfExpr)
+		}
+		i.Else_ifs = elifs
+	}
+	i.Pos, i.End = s.pos(i.Pos), s.end(i.End)
+	return i
+}
+
+func (s mapper) VisitAssignment(a Assignment) any {
+	a.Value = s.expr(a.Value)
+	a.Pos = s.pos(a.Pos)
+	return a
+}
+
+func (s mapper) VisitFnExpr(f FnExpr) any {
+	f.Body = s.stmts(f.Body)
+	f.Pos, f.End = s.pos(f.Pos), s.end(f.End)
+	return f
+}
+
+func (s mapper) VisitCallExpr(c Call) any {
+	c.Callee, c.Arguments = s.expr(c.Callee), s.exprs(c.Arguments)
+	return c
+}
+
+func (s mapper) VisitGet(g Get) any {
+	g.Object = s.expr(g.Object)
+	g.Pos = s.pos(g.Pos)
+	return g
+}
+
+func (s mapper) VisitInstantiate(i Instantiate) any {
+	i.Fn = s.expr(i.Fn)
+	i.Pos = s.pos(i.Pos)
+	return i
+}
+
+func (s mapper) VisitExprStmt(e ExprStmt) any {
+	e.Expr = s.expr(e.Expr)
+	return e
+}
+
+func (s mapper) VisitVarDecl(v VarDecl) any {
+	v.Value = s.expr(v.Value)
+	v.Pos = s.pos(v.Pos)
+	return v
+}
+
+func (s mapper) VisitWhileStmt(w WhileStmt) any {
+	w.Cond = s.expr(w.Cond)
+	w.Body = s.stmts(w.Body)
+	w.Pos = s.pos(w.Pos)
+	return w
+}
+
+func (s mapper) VisitReturnStmt(r Return) any {
+	r.Value = s.expr(r.Value)
+	r.Pos = s.pos(r.Pos)
+	return r
+}
+
+func (s mapper) VisitImport(i Import) any {
+	i.Pos = s.pos(i.Pos)
+	return i
+}
@@ -0,0 +1,184 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast
+
+import (
+	"unicode"
+	"unicode/utf8"
+	"zimlit/graphene/token"
+)
+
+// Span returns where e starts in the source of f and the position just
+// past its end. Nodes only hold the position of one of their tokens, the
+// rest is found in the source lines. An if or a fn spans up to its end, or
+// only its keyword when the position of its end is not known
+func (f File) Span(e Expr) (start Pos, end Pos) {
+	s := e.Accept(spanner{f}).([2]Pos)
+	return s[0], s[1]
+}
+
+type spanner struct {
+	f File
+}
+
+func (s spanner) span(e Expr) (Pos, Pos) {
+	return s.f.Span(e)
+}
+
+func (s spanner) VisitBinary(b Binary) any {
+	start, _ := s.span(b.Left)
+	_, end := s.span(b.Right)
+	return [2]Pos{start, end}
+}
+
+func (s spanner) VisitUnary(u Unary) any {
+	_, end := s.span(u.Right)
+	return [2]Pos{{u.Operator.Line, u.Operator.Col}, end}
+}
+
+func (s spanner) VisitLiteral(l Literal) any {
+	end := l.Pos
+	switch l.Kind {
+	case token.STRING:
+		line := []rune(s.f.line(l.Pos.Line))
+		end.Col = len(line) + 1
+		for i := l.Pos.Col; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == '"' {
+				end.Col = i + 2
+				break
+			}
+		}
+	default:
+		end.Col += utf8.RuneCountInString(l.Value)
+	}
+	return [2]Pos{l.Pos, end}
+}
+
+func (s spanner) VisitGrouping(g Grouping) any {
+	start, end := s.span(g.Inner)
+	return [2]Pos{s.f.before(start, '('), s.f.past(end, ')')}
+}
+
+func (s spanner) VisitIfExpr(i IfExpr) any {
+	return [2]Pos{i.Pos, closed(i.Pos, len("if"), i.End)}
+}
+
+func (s spanner) VisitAssignment(a Assignment) any {
+	_, end := s.span(a.Value)
+	return [2]Pos{a.Pos, end}
+}
+
+func (s spanner) VisitFnExpr(fn FnExpr) any {
+	return [2]Pos{fn.Pos, closed(fn.Pos, len("fn"), fn.End)}
+}
+
+// closed returns the position past the end at end of a node whose keyword
+// of n runes is at pos
+func closed(pos Pos, n int, end Pos) Pos {
+	if end.Line == 0 {
+		return Pos{pos.Line, pos.Col + n}
+	}
+	return Pos{end.Line, end.Col + len("end")}
+}
+
+func (s spanner) VisitCallExpr(c Call) any {
+	start, end := s.span(c.Callee)
+	if len(c.Arguments) == 0 {
+		return [2]Pos{start, s.f.past(s.f.past(end, '('), ')')}
+	}
+	_, end = s.span(c.Arguments[len(c.Arguments)-1])
+	return [2]Pos{start, s.f.past(end, ')')}
+}
+
+func (s spanner) VisitGet(g Get) any {
+	start, _ := s.span(g.Object)
+	end := s.f.skip(Pos{g.Pos.Line, g.Pos.Col + 1})
+	end.Col += utf8.RuneCountInString(g.Name)
+	return [2]Pos{start, end}
+}
+
+func (s spanner) VisitInstantiate(i Instantiate) any {
+	start, _ := s.span(i.Fn)
+	// type arguments are fn types at most, which have no brackets
+	end := i.Pos
+	for p := i.Pos; p.Line <= len(s.f.Lines); p = s.f.next(p) {
+		if s.f.at(p) == ']' {
+			end = Pos{p.Line, p.Col + 1}
+			break
+		}
+	}
+	return [2]Pos{start, end}
+}
+
+// at returns the rune at p, or 0 past the end of its line
+func (f File) at(p Pos) rune {
+	line := []rune(f.line(p.Line))
+	if p.Col < 1 || p.Col > len(line) {
+		return 0
+	}
+	return line[p.Col-1]
+}
+
+// next returns the position of the rune after p, which is on the next line
+// at the end of a line
+func (f File) next(p Pos) Pos {
+	if p.Col > utf8.RuneCountInString(f.line(p.Line)) {
+		return Pos{p.Line + 1, 1}
+	}
+	return Pos{p.Line, p.Col + 1}
+}
+
+// skip returns the position of the first rune from p that is not a space
+func (f File) skip(p Pos) Pos {
+	for ; p.Line <= len(f.Lines); p = f.next(p) {
+		if r := f.at(p); r != 0 && !unicode.IsSpace(r) {
+			return p
+		}
+	}
+	return p
+}
+
+// past returns the position after r if it is the first rune from p that
+// is not a space, otherwise p
+func (f File) past(p Pos, r rune) Pos {
+	if q := f.skip(p); f.at(q) == r {
+		return Pos{q.Line, q.Col + 1}
+	}
+	return p
+}
+
+// before returns the position of r if it is the last rune before p that is
+// not a space, otherwise p
+func (f File) before(p Pos, r rune) Pos {
+	q := p
+	for {
+		if q.Col > 1 {
+			q.Col--
+		} else if q.Line > 1 {
+			q = Pos{q.Line - 1, utf8.RuneCountInString(f.line(q.Line - 1))}
+			if q.Col == 0 {
+				continue
+			}
+		} else {
+			return p
+		}
+		switch c := f.at(q); {
+		case c == r:
+			return q
+		case c != 0 && !unicode.IsSpace(c):
+			return p
+		}
+	}
+}
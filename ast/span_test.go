@@ -0,0 +1,37 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package ast_test
+
+import (
+	"testing"
+	"zimlit/graphene/ast"
+)
+
+func TestSpanThroughEnd(t *testing.T) {
+	f := parse(t, "span.gr", "max(fn (): int 1 end, fn (): int 2 end)\nif x\n\t1\nelse if y\n\t2\nend\n")
+	call := f.Stmts[0].(ast.ExprStmt).Expr.(ast.Call)
+	tests := []struct {
+		expr       ast.Expr
+		start, end ast.Pos
+	}{
+		{call.Arguments[0], ast.Pos{Line: 1, Col: 5}, ast.Pos{Line: 1, Col: 21}},
+		{call.Arguments[1], ast.Pos{Line: 1, Col: 23}, ast.Pos{Line: 1, Col: 39}},
+		{call, ast.Pos{Line: 1, Col: 1}, ast.Pos{Line: 1, Col: 40}},
+		{f.Stmts[1].(ast.ExprStmt).Expr, ast.Pos{Line: 2, Col: 1}, ast.Pos{Line: 6, Col: 4}},
+	}
+	for _, tt := range tests {
+		if start, end := f.Span(tt.expr); start != tt.start || end != tt.end {
+			t.Errorf("%s spans %v to %v, want %v to %v", tt.expr, start, end, tt.start, tt.end)
+		}
+	}
+}
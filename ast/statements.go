@@ -14,184 +14,164 @@ package ast
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
+type ExprStmt struct {
+	Expr Expr
+}
+
+func (e ExprStmt) String() string {
+	return e.Expr.String()
+}
+
+func (e ExprStmt) Position() Pos {
+	return e.Expr.Position()
+}
+
+func (e ExprStmt) Accept(v StmtVisitor[any]) any {
+	return v.VisitExprStmt(e)
+}
+
+func NewExprStmt(expr Expr) ExprStmt {
+	return ExprStmt{expr}
+}
+
+// VarDecl binds Name to Value. Kind is nil until the checker has inferred
+// it when the declaration has no type annotation
 type VarDecl struct {
 	Name   string
 	Kind   ValueKind
 	is_mut bool
+	is_pub bool
 	Value  Expr
+	Pos    Pos
 }
 
 func (v VarDecl) String() string {
-	if !v.is_mut {
-		return fmt.Sprintf("(let %s %s %s)", v.Name, v.Kind.String(), v.Value.String())
+	var str strings.Builder
+	fmt.Fprint(&str, "(let ")
+	if v.is_pub {
+		fmt.Fprint(&str, "pub ")
+	}
+	if v.is_mut {
+		fmt.Fprint(&str, "mut ")
+	}
+	if v.Kind == nil {
+		fmt.Fprintf(&str, "%s _ %s)", v.Name, v.Value.String())
 	} else {
-		return fmt.Sprintf("(let mut %s %s %s)", v.Name, v.Kind.String(), v.Value.String())
+		fmt.Fprintf(&str, "%s %s %s)", v.Name, v.Kind.String(), v.Value.String())
 	}
+	return str.String()
 }
 
-func (va VarDecl) Accept(v Visitor[any]) any {
-	return v.visitVarDecl(va)
+func (v VarDecl) IsMut() bool {
+	return v.is_mut
 }
 
-func NewVarDecl(name string, kind ValueKind, value Expr, is_mut bool) VarDecl {
-	return VarDecl{
-		Name:   name,
-		Kind:   kind,
-		is_mut: is_mut,
-		Value:  value,
-	}
+func (v VarDecl) IsPub() bool {
+	return v.is_pub
 }
 
-type IfExpr struct {
-	Condition Expr
-	Body      []Expr
-	Else_ifs  []IfExpr
-	Else      []Expr
+func (v VarDecl) Export() VarDecl {
+	v.is_pub = true
+	return v
 }
 
-func (i IfExpr) String() string {
-	var str strings.Builder
-
-	fmt.Fprintf(&str, "(if %s (", i.Condition.String())
-	for j, b := range i.Body {
-		fmt.Fprint(&str, b)
-		if j+1 != len(i.Body) {
-			fmt.Fprint(&str, " ")
-		}
-	}
-	if i.Else_ifs == nil {
-		fmt.Fprint(&str, ")")
-	} else {
-		fmt.Fprint(&str, ") ")
-	}
-
-	for _, e := range i.Else_ifs {
-		fmt.Fprintf(&str, "%s ", e.String())
-	}
-	if i.Else != nil {
-		fmt.Fprint(&str, "(")
-	}
-	for _, e := range i.Else {
-		fmt.Fprintf(&str, "%s", e.String())
-	}
-	if i.Else != nil {
-		fmt.Fprint(&str, ")")
-	}
-	fmt.Fprint(&str, ")")
-
-	return str.String()
+func (v VarDecl) Position() Pos {
+	return v.Pos
 }
 
-func (i IfExpr) Accept(v Visitor[any]) any {
-	return v.visitIfExpr(i)
+func (va VarDecl) Accept(v StmtVisitor[any]) any {
+	return v.VisitVarDecl(va)
 }
 
-func NewIfExpr(condition Expr, body []Expr, else_ifs []IfExpr, el []Expr) IfExpr {
-	return IfExpr{
-		Condition: condition,
-		Body:      body,
-		Else_ifs:  else_ifs,
-		Else:      el,
+func NewVarDecl(name string, kind ValueKind, value Expr, is_mut bool, pos Pos) VarDecl {
+	return VarDecl{
+		Name:   name,
+		Kind:   kind,
+		is_mut: is_mut,
+		Value:  value,
+		Pos:    pos,
 	}
 }
 
-type WhileExpr struct {
+type WhileStmt struct {
 	Cond Expr
-	Body []Expr
+	Body []Stmt
+	Pos  Pos
 }
 
-func (w WhileExpr) String() string {
-	var str strings.Builder
-	fmt.Fprintf(&str, "(while %s (", w.Cond.String())
-	for i, e := range w.Body {
-		fmt.Fprint(&str, e)
-		if i+1 != len(w.Body) {
-			fmt.Fprint(&str, " ")
-		}
-	}
-	fmt.Fprintf(&str, "))")
+func (w WhileStmt) String() string {
+	return fmt.Sprintf("(while %s %s)", w.Cond.String(), stmtsString(w.Body))
+}
 
-	return str.String()
+func (w WhileStmt) Position() Pos {
+	return w.Pos
 }
 
-func (w WhileExpr) Accept(v Visitor[any]) any {
-	return v.visitWhileExpr(w)
+func (w WhileStmt) Accept(v StmtVisitor[any]) any {
+	return v.VisitWhileStmt(w)
 }
 
-func NewWhileExpr(cond Expr, body []Expr) WhileExpr {
-	return WhileExpr{
+func NewWhileStmt(cond Expr, body []Stmt, pos Pos) WhileStmt {
+	return WhileStmt{
 		Cond: cond,
 		Body: body,
+		Pos:  pos,
 	}
 }
 
-type Param struct {
-	Name string
-	Kind ValueKind
+type Return struct {
+	Value Expr
+	Pos   Pos
 }
 
-func NewParam(name string, kind ValueKind) Param {
-	return Param{
-		Name: name,
-		Kind: kind,
-	}
+func (r Return) String() string {
+	return fmt.Sprintf("(return %s)", r.Value.String())
 }
 
-type FnExpr struct {
-	Params []Param
-	Body   []Expr
-	Rtype  ValueKind
+func (r Return) Position() Pos {
+	return r.Pos
 }
 
-func (f FnExpr) String() string {
-	var str strings.Builder
-	fmt.Fprintf(&str, "(fn %s (", f.Rtype.String())
-	for i, e := range f.Params {
-		fmt.Fprintf(&str, "%s: %s", e.Name, e.Kind.String())
-		if i+1 != len(f.Params) {
-			fmt.Fprint(&str, " ")
-		}
-	}
-	fmt.Fprint(&str, ") (")
-	for i, e := range f.Body {
-		fmt.Fprint(&str, e)
-		if i+1 != len(f.Body) {
-			fmt.Fprint(&str, " ")
-		}
+func (r Return) Accept(v StmtVisitor[any]) any {
+	return v.VisitReturnStmt(r)
+}
+
+func NewReturn(value Expr, pos Pos) Return {
+	return Return{
+		Value: value,
+		Pos:   pos,
 	}
-	fmt.Fprintf(&str, "))")
-	return str.String()
 }
 
-func (f FnExpr) Accept(v Visitor[any]) any {
-	return v.visitFnExpr(f)
+type Import struct {
+	Path string
+	Pos  Pos
 }
 
-func NewFn(params []Param, body []Expr, rtype ValueKind) FnExpr {
-	return FnExpr{
-		Params: params,
-		Body:   body,
-		Rtype:  rtype,
-	}
+// Name is the name the module is bound to, the last element of its path
+func (i Import) Name() string {
+	return path.Base(i.Path)
 }
 
-type Return struct {
-	Value Expr
+func (i Import) String() string {
+	return fmt.Sprintf("(import %s)", quote(i.Path))
 }
 
-func (r Return) String() string {
-	return fmt.Sprintf("(return %s)", r.Value.String())
+func (i Import) Position() Pos {
+	return i.Pos
 }
 
-func (r Return) Accept(v Visitor[any]) any {
-	return v.visitReturnExpr(r)
+func (i Import) Accept(v StmtVisitor[any]) any {
+	return v.VisitImport(i)
 }
 
-func NewReturn(value Expr) Return {
-	return Return{
-		Value: value,
+func NewImport(path string, pos Pos) Import {
+	return Import{
+		Path: path,
+		Pos:  pos,
 	}
 }
@@ -0,0 +1,258 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package builtins implements the functions predeclared in the global scope
+// of every graphene program. Each builtin carries an ast.Fn signature so the
+// checker validates calls to it like any other fn
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"zimlit/graphene/ast"
+)
+
+// Host is the environment builtins run in
+type Host struct {
+	Stdout io.Writer
+	// Args are the command line arguments passed to the program
+	Args []string
+}
+
+// Exit is returned by the exit builtin to stop the program with Code as its
+// exit status
+type Exit struct {
+	Code int
+}
+
+func (e Exit) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+type Builtin struct {
+	Name string
+	Kind ast.Fn
+	Fn   func(h *Host, args []any) (any, error)
+}
+
+func (b *Builtin) String() string {
+	return fmt.Sprintf("<builtin %s %s>", b.Name, b.Kind)
+}
+
+// Call runs b with args. The checker lets nil through as a value of any
+// kind, so an argument for a parameter of a concrete kind is checked here
+// and a nil one is an error
+func (b *Builtin) Call(h *Host, args []any) (any, error) {
+	for i, p := range b.Kind.Params {
+		if _, generic := p.Kind.(ast.TypeVar); !generic && args[i] == nil {
+			return nil, fmt.Errorf("nil passed as argument %d of %s, expected %s", i+1, b.Name, p.Kind)
+		}
+	}
+	return b.Fn(h, args)
+}
+
+func params(kinds ...ast.ValueKind) []ast.Param {
+	ps := make([]ast.Param, len(kinds))
+	for i, k := range kinds {
+		ps[i] = ast.NewParam("", k)
+	}
+	return ps
+}
+
+func fn(rtype ast.ValueKind, kinds ...ast.ValueKind) ast.Fn {
+	return ast.NewFnT(nil, params(kinds...), rtype)
+}
+
+// generic is the kind of a fn with a single type parameter T
+func generic(constraint ast.Constraint, rtype ast.ValueKind, kinds ...ast.ValueKind) ast.Fn {
+	return ast.NewFnT([]ast.TypeParam{ast.NewTypeParam("T", constraint)}, params(kinds...), rtype)
+}
+
+var t = ast.TypeVar{Name: "T"}
+
+var builtins = []*Builtin{
+	{"print", generic(ast.ANY, ast.NIL, t), printFn("")},
+	{"println", generic(ast.ANY, ast.NIL, t), printFn("\n")},
+	{"len", fn(ast.INT, ast.STRING), length},
+	{"concat", fn(ast.STRING, ast.STRING, ast.STRING), concat},
+	{"substr", fn(ast.STRING, ast.STRING, ast.INT, ast.INT), substr},
+	{"split", fn(ast.STRING, ast.STRING, ast.STRING, ast.INT), split},
+	{"to_upper", fn(ast.STRING, ast.STRING), toUpper},
+	{"int", generic(ast.COMPARABLE, ast.INT, t), toInt},
+	{"float", generic(ast.COMPARABLE, ast.FLOAT, t), toFloat},
+	{"string", generic(ast.ANY, ast.STRING, t), toString},
+	{"sqrt", fn(ast.FLOAT, ast.FLOAT), math1(math.Sqrt)},
+	{"floor", fn(ast.FLOAT, ast.FLOAT), math1(math.Floor)},
+	{"pow", fn(ast.FLOAT, ast.FLOAT, ast.FLOAT), pow},
+	{"abs", generic(ast.NUMERIC, t, t), abs},
+	{"nargs", fn(ast.INT), nargs},
+	{"arg", fn(ast.STRING, ast.INT), arg},
+	{"exit", fn(ast.NIL, ast.INT), exit},
+}
+
+// All returns the builtins in declaration order
+func All() []*Builtin {
+	return builtins
+}
+
+// Format returns the text print writes for v
+func Format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEnNI") {
+			s += ".0"
+		}
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func printFn(end string) func(h *Host, args []any) (any, error) {
+	return func(h *Host, args []any) (any, error) {
+		_, err := io.WriteString(h.Stdout, Format(args[0])+end)
+		return nil, err
+	}
+}
+
+// length returns the number of runes in s, a byte that is not valid UTF-8
+// counts as one rune
+func length(h *Host, args []any) (any, error) {
+	return int64(utf8.RuneCountInString(args[0].(string))), nil
+}
+
+func concat(h *Host, args []any) (any, error) {
+	return args[0].(string) + args[1].(string), nil
+}
+
+// substr returns the runes of s in [start, end), counted like length
+func substr(h *Host, args []any) (any, error) {
+	s, start, end := args[0].(string), args[1].(int64), args[2].(int64)
+	n := int64(utf8.RuneCountInString(s))
+	if start < 0 || end < start || end > n {
+		return nil, fmt.Errorf("substr bounds [%d:%d] out of range for string of length %d", start, end, n)
+	}
+	return s[runeOffset(s, start):runeOffset(s, end)], nil
+}
+
+// runeOffset returns the byte offset of rune i of s
+func runeOffset(s string, i int64) int {
+	offset := 0
+	for ; i > 0; i-- {
+		_, size := utf8.DecodeRuneInString(s[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// split returns field i of s split around sep, there are no arrays to return
+// every field at once
+func split(h *Host, args []any) (any, error) {
+	s, sep, i := args[0].(string), args[1].(string), args[2].(int64)
+	fields := strings.Split(s, sep)
+	if i < 0 || i >= int64(len(fields)) {
+		return nil, fmt.Errorf("split index %d out of range for %d fields", i, len(fields))
+	}
+	return fields[i], nil
+}
+
+func toUpper(h *Host, args []any) (any, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+func toInt(h *Host, args []any) (any, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("cannot convert %s to int", Format(v))
+		}
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to int", v)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to int", Format(args[0]))
+}
+
+func toFloat(h *Host, args []any) (any, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to float", v)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to float", Format(args[0]))
+}
+
+func toString(h *Host, args []any) (any, error) {
+	return Format(args[0]), nil
+}
+
+func math1(f func(float64) float64) func(h *Host, args []any) (any, error) {
+	return func(h *Host, args []any) (any, error) {
+		return f(args[0].(float64)), nil
+	}
+}
+
+func pow(h *Host, args []any) (any, error) {
+	return math.Pow(args[0].(float64), args[1].(float64)), nil
+}
+
+func abs(h *Host, args []any) (any, error) {
+	switch v := args[0].(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, errors.New("abs of minimum int overflows")
+		}
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	case float64:
+		return math.Abs(v), nil
+	}
+	return nil, fmt.Errorf("abs of %s", Format(args[0]))
+}
+
+func nargs(h *Host, args []any) (any, error) {
+	return int64(len(h.Args)), nil
+}
+
+func arg(h *Host, args []any) (any, error) {
+	i := args[0].(int64)
+	if i < 0 || i >= int64(len(h.Args)) {
+		return nil, fmt.Errorf("arg index %d out of range for %d args", i, len(h.Args))
+	}
+	return h.Args[i], nil
+}
+
+func exit(h *Host, args []any) (any, error) {
+	return nil, Exit{int(args[0].(int64))}
+}
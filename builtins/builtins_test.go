@@ -0,0 +1,101 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package builtins_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"zimlit/graphene/builtins"
+)
+
+func call(t *testing.T, name string, args ...any) (any, error) {
+	t.Helper()
+	for _, b := range builtins.All() {
+		if b.Name == name {
+			return b.Call(&builtins.Host{}, args)
+		}
+	}
+	t.Fatalf("no builtin %s", name)
+	return nil, nil
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		name string
+		args []any
+		want any
+		err  string
+	}{
+		{"len", []any{""}, int64(0), ""},
+		{"len", []any{"héllo"}, int64(5), ""},
+		{"len", []any{"é"}, int64(1), ""},
+		{"len", []any{"\xffa"}, int64(2), ""},
+		{"substr", []any{"héllo", int64(0), int64(2)}, "hé", ""},
+		{"substr", []any{"héllo", int64(1), int64(5)}, "éllo", ""},
+		{"substr", []any{"héllo", int64(2), int64(2)}, "", ""},
+		{"substr", []any{"a\xffb", int64(1), int64(2)}, "\xff", ""},
+		{"substr", []any{"héllo", int64(0), int64(6)}, nil, "substr bounds [0:6] out of range for string of length 5"},
+		{"substr", []any{"héllo", int64(-1), int64(2)}, nil, "out of range"},
+		{"substr", []any{"héllo", int64(3), int64(2)}, nil, "out of range"},
+		{"split", []any{"a,b,,c", ",", int64(0)}, "a", ""},
+		{"split", []any{"a,b,,c", ",", int64(2)}, "", ""},
+		{"split", []any{"a,b,,c", ",", int64(3)}, "c", ""},
+		{"split", []any{"abc", "", int64(1)}, "b", ""},
+		{"split", []any{"a,b", ",", int64(2)}, nil, "split index 2 out of range for 2 fields"},
+		{"split", []any{"a,b", ",", int64(-1)}, nil, "out of range"},
+		{"int", []any{int64(3)}, int64(3), ""},
+		{"int", []any{2.9}, int64(2), ""},
+		{"int", []any{-2.9}, int64(-2), ""},
+		{"int", []any{" 42\n"}, int64(42), ""},
+		{"int", []any{"4.2"}, nil, `cannot convert "4.2" to int`},
+		{"int", []any{math.NaN()}, nil, "cannot convert NaN to int"},
+		{"int", []any{math.Inf(1)}, nil, "cannot convert +Inf to int"},
+		{"float", []any{int64(3)}, 3.0, ""},
+		{"float", []any{"2.5"}, 2.5, ""},
+		{"float", []any{"x"}, nil, `cannot convert "x" to float`},
+		{"string", []any{int64(-7)}, "-7", ""},
+		{"string", []any{2.0}, "2.0", ""},
+		{"string", []any{0.5}, "0.5", ""},
+		{"string", []any{math.NaN()}, "NaN", ""},
+		{"string", []any{math.Inf(-1)}, "-Inf", ""},
+		{"string", []any{nil}, "nil", ""},
+		{"string", []any{"s"}, "s", ""},
+		{"sqrt", []any{16.0}, 4.0, ""},
+		{"floor", []any{-1.5}, -2.0, ""},
+		{"pow", []any{2.0, 10.0}, 1024.0, ""},
+		{"abs", []any{int64(-3)}, int64(3), ""},
+		{"abs", []any{-2.5}, 2.5, ""},
+		{"abs", []any{int64(math.MinInt64)}, nil, "abs of minimum int overflows"},
+		{"len", []any{nil}, nil, "nil passed as argument 1 of len, expected string"},
+	}
+	for _, tt := range tests {
+		got, err := call(t, tt.name, tt.args...)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s%q gave error %v, want %q", tt.name, tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s%q = %#v, %v, want %#v", tt.name, tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestSqrtOfNegative(t *testing.T) {
+	got, err := call(t, "sqrt", -1.0)
+	if f, ok := got.(float64); err != nil || !ok || !math.IsNaN(f) {
+		t.Errorf("sqrt(-1.0) = %v, %v, want NaN", got, err)
+	}
+}
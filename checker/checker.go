@@ -0,0 +1,766 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package checker
+
+import (
+	"sort"
+	"unicode/utf8"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/token"
+)
+
+type Checker struct {
+	lines   []string
+	fname   string
+	scopes  []*scope
+	fn      *function
+	modules map[string]Module
+	// input counts the inputs of an interactive session
+	input int
+	// type parameters of the generic fns being checked
+	typeParams []ast.TypeParam
+	// hoisted are the symbols of the top level fn declarations, declared
+	// before any statement is checked, by the position of their declaration
+	hoisted map[ast.Pos]*symbol
+	errs    CheckError
+}
+
+func NewChecker(lines []string, fname string) Checker {
+	globals := newScope(nil)
+	for _, b := range builtins.All() {
+		globals.symbols[b.Name] = &symbol{kind: b.Kind, ready: true, builtin: true}
+	}
+	return Checker{
+		lines:   lines,
+		fname:   fname,
+		scopes:  []*scope{globals},
+		modules: make(map[string]Module),
+	}
+}
+
+// AddModule makes m available to import declarations
+func (c *Checker) AddModule(m Module) {
+	c.modules[m.Path] = m
+}
+
+// Module returns the interface of the checked file, made of its top level
+// pub bindings
+func (c *Checker) Module(path string) Module {
+	m := Module{
+		Path:    path,
+		Exports: make(map[string]ast.ValueKind),
+	}
+	for name, sym := range c.scopes[0].symbols {
+		if sym.pub {
+			m.Exports[name] = sym.kind
+		}
+	}
+
+	return m
+}
+
+// Binding is a global binding of a checker
+type Binding struct {
+	Name    string
+	Kind    ast.ValueKind
+	Mut     bool
+	Builtin bool
+}
+
+// Bindings returns the global bindings sorted by name
+func (c *Checker) Bindings() []Binding {
+	var bindings []Binding
+	for name, sym := range c.scopes[0].symbols {
+		bindings = append(bindings, Binding{name, sym.kind, sym.mut, sym.builtin})
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].Name < bindings[j].Name
+	})
+	return bindings
+}
+
+type result struct {
+	expr ast.Expr
+	kind ast.ValueKind
+}
+
+// Check resolves and type checks stmts, returning a copy of the tree
+// annotated with the information needed by the interpreter
+func (c *Checker) Check(stmts ast.Stmts) (ast.Stmts, error) {
+	c.errs = nil
+	c.hoist(stmts)
+	checked := ast.Stmts{}
+	for _, stmt := range stmts {
+		s, _ := c.checkStmt(stmt)
+		checked = append(checked, s)
+	}
+
+	if c.errs != nil {
+		return nil, c.errs
+	}
+	return checked, nil
+}
+
+// CheckInput checks one input of an interactive session, which can use the
+// bindings of the inputs before it, and returns the kind of its last
+// statement. lines and fname are the source of the input. The bindings of an
+// input that fails to check are discarded
+func (c *Checker) CheckInput(stmts ast.Stmts, lines []string, fname string) (ast.Stmts, ast.ValueKind, error) {
+	c.lines, c.fname = lines, fname
+	c.input++
+	c.errs = nil
+	saved := c.SaveGlobals()
+	c.hoist(stmts)
+	checked, kind := c.stmts(stmts)
+
+	if c.errs != nil {
+		c.RestoreGlobals(saved)
+		return nil, nil, c.errs
+	}
+	return checked, kind, nil
+}
+
+// Globals is a copy of the global bindings of a checker
+type Globals struct {
+	symbols map[string]*symbol
+}
+
+func (c *Checker) SaveGlobals() Globals {
+	g := Globals{make(map[string]*symbol)}
+	for name, sym := range c.scopes[0].symbols {
+		g.symbols[name] = sym
+	}
+	return g
+}
+
+// RestoreGlobals replaces the global bindings with g, undoing the
+// declarations made since it was saved
+func (c *Checker) RestoreGlobals(g Globals) {
+	c.scopes[0].symbols = make(map[string]*symbol)
+	for name, sym := range g.symbols {
+		c.scopes[0].symbols[name] = sym
+	}
+}
+
+// hoist declares the fns declared at the top level of stmts, so that fns
+// can call the fns declared after them. Globals are looked up when a fn
+// runs, so such calls work as long as the fn is declared before it is run
+func (c *Checker) hoist(stmts ast.Stmts) {
+	c.hoisted = make(map[ast.Pos]*symbol)
+	for _, stmt := range stmts {
+		v, ok := stmt.(ast.VarDecl)
+		if !ok || v.IsMut() {
+			continue
+		}
+		if _, ok := v.Value.(ast.FnExpr); !ok {
+			continue
+		}
+		if _, ok := v.Kind.(ast.Fn); !ok {
+			continue
+		}
+		sym := &symbol{kind: v.Kind, pub: v.IsPub(), ready: true, decl: v.Pos, input: c.input}
+		c.declare(v.Name, sym, v.Pos)
+		c.hoisted[v.Pos] = sym
+	}
+}
+
+func (c *Checker) check(expr ast.Expr) (ast.Expr, ast.ValueKind) {
+	r := expr.Accept(c).(result)
+	return r.expr, r.kind
+}
+
+// error reports an error at the rune at pos
+func (c *Checker) error(code diag.Code, pos ast.Pos, format string, args ...any) *CheckErr {
+	return c.errorSpan(code, pos, ast.Pos{}, format, args...)
+}
+
+// errorSpan reports an error about the source from start up to end
+func (c *Checker) errorSpan(code diag.Code, start ast.Pos, end ast.Pos, format string, args ...any) *CheckErr {
+	err := c.newCheckErr(code, start, end, format, args...)
+	c.errs = append(c.errs, err)
+	return err
+}
+
+// errorIn reports an error about all of e
+func (c *Checker) errorIn(code diag.Code, e ast.Expr, format string, args ...any) *CheckErr {
+	start, end := c.file().Span(e)
+	return c.errorSpan(code, start, end, format, args...)
+}
+
+func (c *Checker) file() ast.File {
+	return ast.File{Name: c.fname, Lines: c.lines}
+}
+
+// nameSpan returns the span of name written at pos
+func nameSpan(pos ast.Pos, name string) (ast.Pos, ast.Pos) {
+	return pos, ast.Pos{Line: pos.Line, Col: pos.Col + utf8.RuneCountInString(name)}
+}
+
+// declared points err at the declaration of name when it is part of the
+// source being checked
+func (c *Checker) declared(err *CheckErr, name string, sym *symbol, format string, args ...any) {
+	if sym.decl.Line == 0 || sym.input != c.input {
+		return
+	}
+	start, end := nameSpan(sym.decl, name)
+	err.label(start, end, format, args...)
+}
+
+// block checks body in a new scope, the kind of a block is the kind of its
+// last statement
+func (c *Checker) block(body []ast.Stmt) ([]ast.Stmt, ast.ValueKind) {
+	c.beginScope()
+	defer c.endScope()
+
+	return c.stmts(body)
+}
+
+func (c *Checker) stmts(body []ast.Stmt) ([]ast.Stmt, ast.ValueKind) {
+	var checked []ast.Stmt
+	var kind ast.ValueKind = ast.NIL
+	for _, stmt := range body {
+		var s ast.Stmt
+		s, kind = c.checkStmt(stmt)
+		checked = append(checked, s)
+	}
+	if checked == nil && body != nil {
+		checked = []ast.Stmt{}
+	}
+
+	return checked, kind
+}
+
+func (c *Checker) condition(kind ast.ValueKind, cond ast.Expr) {
+	if kind != nil && !sameKind(kind, ast.INT) {
+		c.errorIn(diag.NonIntCondition, cond, "condition must be int, got %s", kind)
+	}
+}
+
+func sameKind(a, b ast.ValueKind) bool {
+	switch a := a.(type) {
+	case ast.Const:
+		b, ok := b.(ast.Const)
+		return ok && a == b
+	case ast.TypeVar:
+		b, ok := b.(ast.TypeVar)
+		return ok && a.Name == b.Name
+	case ast.Fn:
+		b, ok := b.(ast.Fn)
+		if !ok || len(a.TypeParams) != len(b.TypeParams) || len(a.Params) != len(b.Params) || !sameKind(a.Rtype, b.Rtype) {
+			return false
+		}
+		for i := range a.Params {
+			if !sameKind(a.Params[i].Kind, b.Params[i].Kind) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// assignable reports whether a value of kind src may be stored where dst is
+// expected. nil fits anywhere, and a missing kind means an error has already
+// been reported for the value
+func assignable(dst ast.ValueKind, src ast.ValueKind) bool {
+	if dst == nil || src == nil || src == ast.NIL {
+		return true
+	}
+	return sameKind(dst, src)
+}
+
+func isOneOf(kind ast.ValueKind, consts ...ast.Const) bool {
+	k, ok := kind.(ast.Const)
+	if !ok {
+		return false
+	}
+	for _, c := range consts {
+		if k == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Checker) typeParam(name string) (ast.TypeParam, bool) {
+	for i := len(c.typeParams) - 1; i >= 0; i-- {
+		if c.typeParams[i].Name == name {
+			return c.typeParams[i], true
+		}
+	}
+
+	return ast.TypeParam{}, false
+}
+
+func (c *Checker) validKind(kind ast.ValueKind, pos ast.Pos) bool {
+	switch k := kind.(type) {
+	case ast.TypeVar:
+		if _, ok := c.typeParam(k.Name); !ok {
+			c.error(diag.UndefinedType, pos, "undefined type %s", k.Name)
+			return false
+		}
+	case ast.Fn:
+		n := len(c.typeParams)
+		c.typeParams = append(c.typeParams, k.TypeParams...)
+		defer func() { c.typeParams = c.typeParams[:n] }()
+		valid := true
+		for _, p := range k.Params {
+			valid = c.validKind(p.Kind, pos) && valid
+		}
+		return c.validKind(k.Rtype, pos) && valid
+	}
+
+	return true
+}
+
+// satisfies reports whether values of kind support the operations allowed
+// by constraint. comparable kinds support == and !=, numeric kinds support
+// comparisons and arithmetic
+func (c *Checker) satisfies(kind ast.ValueKind, constraint ast.Constraint) bool {
+	switch k := kind.(type) {
+	case ast.Const:
+		switch constraint {
+		case ast.COMPARABLE:
+			return isOneOf(k, ast.INT, ast.FLOAT, ast.STRING)
+		case ast.NUMERIC:
+			return isOneOf(k, ast.INT, ast.FLOAT)
+		}
+	case ast.TypeVar:
+		p, _ := c.typeParam(k.Name)
+		return constraint == ast.ANY || p.Constraint == constraint ||
+			(constraint == ast.COMPARABLE && p.Constraint == ast.NUMERIC)
+	}
+
+	return constraint == ast.ANY
+}
+
+// unify binds the type parameters of a generic fn appearing in param to the
+// matching parts of arg
+func unify(param ast.ValueKind, arg ast.ValueKind, typeParams []ast.TypeParam, bindings map[string]ast.ValueKind) {
+	switch p := param.(type) {
+	case ast.TypeVar:
+		if _, ok := bindings[p.Name]; ok || arg == nil || arg == ast.NIL {
+			return
+		}
+		for _, tp := range typeParams {
+			if tp.Name == p.Name {
+				bindings[p.Name] = arg
+			}
+		}
+	case ast.Fn:
+		a, ok := arg.(ast.Fn)
+		if !ok || len(a.Params) != len(p.Params) {
+			return
+		}
+		for i := range p.Params {
+			unify(p.Params[i].Kind, a.Params[i].Kind, typeParams, bindings)
+		}
+		unify(p.Rtype, a.Rtype, typeParams, bindings)
+	}
+}
+
+func subst(kind ast.ValueKind, bindings map[string]ast.ValueKind) ast.ValueKind {
+	switch k := kind.(type) {
+	case ast.TypeVar:
+		if b, ok := bindings[k.Name]; ok {
+			return b
+		}
+	case ast.Fn:
+		params := []ast.Param{}
+		for _, p := range k.Params {
+			params = append(params, ast.NewParam(p.Name, subst(p.Kind, bindings)))
+		}
+		return ast.NewFnT(k.TypeParams, params, subst(k.Rtype, bindings))
+	}
+
+	return kind
+}
+
+// instantiate substitutes the type parameters of fnT after checking the
+// bound kinds satisfy their constraints, errors are reported about at
+func (c *Checker) instantiate(fnT ast.Fn, bindings map[string]ast.ValueKind, name string, at ast.Expr) (ast.Fn, bool) {
+	ok := true
+	for _, tp := range fnT.TypeParams {
+		kind := bindings[tp.Name]
+		if !c.satisfies(kind, tp.Constraint) {
+			c.errorIn(diag.UnsatisfiedConstraint, at, "%s does not satisfy %s, required by type parameter %s of %s", kind, tp.Constraint, tp.Name, name)
+			ok = false
+		}
+	}
+
+	generic := ast.NewFnT(nil, fnT.Params, fnT.Rtype)
+	return subst(generic, bindings).(ast.Fn), ok
+}
+
+func (c *Checker) VisitBinary(b ast.Binary) any {
+	left, lk := c.check(b.Left)
+	right, rk := c.check(b.Right)
+	b.Left, b.Right = left, right
+	if lk == nil || rk == nil {
+		return result{b, nil}
+	}
+
+	switch b.Operator.Kind {
+	case token.PLUS:
+		if sameKind(lk, rk) && (c.satisfies(lk, ast.NUMERIC) || isOneOf(lk, ast.STRING)) {
+			return result{b, lk}
+		}
+	case token.MINUS, token.STAR, token.SLASH:
+		if sameKind(lk, rk) && c.satisfies(lk, ast.NUMERIC) {
+			return result{b, lk}
+		}
+	case token.PERCENT, token.AMP, token.PIPE, token.CARET, token.SHL, token.SHR:
+		if sameKind(lk, ast.INT) && sameKind(rk, ast.INT) {
+			return result{b, ast.INT}
+		}
+		c.operands(b, lk, rk, diag.InvalidOperation, "operator %s is only defined on int, got %s and %s", b.Operator.Kind, lk, rk)
+		return result{b, nil}
+	case token.EQEQ, token.NEQ:
+		if lk == ast.NIL || rk == ast.NIL || (sameKind(lk, rk) && c.satisfies(lk, ast.COMPARABLE)) {
+			return result{b, ast.INT}
+		}
+	case token.LESS, token.LESSEQ, token.GREATER, token.GREATEREQ:
+		if sameKind(lk, rk) && (c.satisfies(lk, ast.NUMERIC) || isOneOf(lk, ast.STRING)) {
+			return result{b, ast.INT}
+		}
+	}
+
+	c.operands(b, lk, rk, diag.InvalidOperation, "invalid operation %s on %s and %s", b.Operator.Kind, lk, rk)
+	return result{b, nil}
+}
+
+// operands reports an error at the operator of b that points at each of its
+// operands with their kinds
+func (c *Checker) operands(b ast.Binary, lk ast.ValueKind, rk ast.ValueKind, code diag.Code, format string, args ...any) {
+	start, end := nameSpan(b.Position(), b.Operator.Literal)
+	err := c.errorSpan(code, start, end, format, args...)
+	start, end = c.file().Span(b.Left)
+	err.label(start, end, "%s", lk)
+	start, end = c.file().Span(b.Right)
+	err.label(start, end, "%s", rk)
+}
+
+func (c *Checker) VisitUnary(u ast.Unary) any {
+	right, kind := c.check(u.Right)
+	u.Right = right
+	if kind == nil {
+		return result{u, nil}
+	}
+
+	switch u.Operator.Kind {
+	case token.MINUS:
+		if c.satisfies(kind, ast.NUMERIC) {
+			return result{u, kind}
+		}
+	case token.BANG:
+		if isOneOf(kind, ast.INT) {
+			return result{u, kind}
+		}
+	case token.TILDE:
+		if isOneOf(kind, ast.INT) {
+			return result{u, kind}
+		}
+		c.errorIn(diag.InvalidOperation, u, "operator ~ is only defined on int, got %s", kind)
+		return result{u, nil}
+	}
+
+	c.errorIn(diag.InvalidOperation, u, "invalid operation %s on %s", u.Operator.Kind, kind)
+	return result{u, nil}
+}
+
+func (c *Checker) VisitLiteral(l ast.Literal) any {
+	switch l.Kind {
+	case token.INT:
+		return result{l, ast.INT}
+	case token.FLOAT:
+		return result{l, ast.FLOAT}
+	case token.STRING:
+		return result{l, ast.STRING}
+	case token.NIL:
+		return result{l, ast.NIL}
+	}
+
+	sym, sameFn := c.resolve(l.Value)
+	if sym == nil {
+		c.errorIn(diag.Undefined, l, "undefined: %s", l.Value)
+		return result{l, nil}
+	}
+	if sym.module != nil {
+		c.errorIn(diag.ModuleAsValue, l, "use of module %s without selector", l.Value)
+		return result{l, nil}
+	}
+	if !sym.ready && sameFn {
+		c.errorIn(diag.UsedInInitializer, l, "%s used in its own initializer", l.Value)
+		return result{l, nil}
+	}
+
+	return result{l, sym.kind}
+}
+
+func (c *Checker) VisitGrouping(g ast.Grouping) any {
+	inner, kind := c.check(g.Inner)
+	g.Inner = inner
+	return result{g, kind}
+}
+
+func (c *Checker) VisitAssignment(a ast.Assignment) any {
+	sym, _ := c.resolve(a.Name)
+	value, kind := c.check(a.Value)
+	a.Value = value
+
+	start, end := nameSpan(a.Pos, a.Name)
+	if sym == nil {
+		c.errorSpan(diag.Undefined, start, end, "undefined: %s", a.Name)
+		return result{a, nil}
+	}
+	if sym.module != nil || !sym.mut {
+		err := c.errorSpan(diag.AssignToImmutable, start, end, "cannot assign to %s, it is not declared mut", a.Name)
+		c.declared(err, a.Name, sym, "declared here")
+		if sym.module == nil && !sym.builtin {
+			err.help("declare it with let mut %s to allow assigning to it", a.Name)
+		}
+	} else if !assignable(sym.kind, kind) {
+		err := c.errorIn(diag.MismatchedTypes, a.Value, "cannot assign %s value to %s of type %s", kind, a.Name, sym.kind)
+		c.declared(err, a.Name, sym, "declared as %s here", sym.kind)
+	}
+
+	return result{a, sym.kind}
+}
+
+func (c *Checker) VisitFnExpr(f ast.FnExpr) any {
+	n := len(c.typeParams)
+	c.typeParams = append(c.typeParams, f.TypeParams...)
+	defer func() { c.typeParams = c.typeParams[:n] }()
+	for _, p := range f.Params {
+		c.validKind(p.Kind, f.Pos)
+	}
+	c.validKind(f.Rtype, f.Pos)
+
+	fn := &function{rtype: f.Rtype, pos: f.Pos, enclosing: c.fn}
+	c.fn = fn
+	c.beginScope()
+	for _, p := range f.Params {
+		c.declare(p.Name, &symbol{kind: p.Kind, ready: true}, f.Pos)
+	}
+
+	body, kind := c.stmts(f.Body)
+	if len(body) > 0 {
+		if _, ok := body[len(body)-1].(ast.Return); !ok && !assignable(f.Rtype, kind) {
+			err := c.errorIn(diag.MismatchedTypes, f, "fn returns %s but its body evaluates to %s", f.Rtype, kind)
+			if e, ok := body[len(body)-1].(ast.ExprStmt); ok {
+				start, end := c.file().Span(e.Expr)
+				err.label(start, end, "%s", kind)
+			}
+		}
+	}
+
+	c.endScope()
+	c.fn = fn.enclosing
+	f.Body = body
+	f.Captures = fn.captures
+
+	return result{f, ast.NewFnT(f.TypeParams, f.Params, f.Rtype)}
+}
+
+func (c *Checker) VisitCallExpr(cl ast.Call) any {
+	callee, ck := c.check(cl.Callee)
+	args := []ast.Expr{}
+	kinds := []ast.ValueKind{}
+	for _, arg := range cl.Arguments {
+		a, k := c.check(arg)
+		args = append(args, a)
+		kinds = append(kinds, k)
+	}
+	cl.Callee, cl.Arguments = callee, args
+	if ck == nil {
+		return result{cl, nil}
+	}
+
+	fnT, ok := ck.(ast.Fn)
+	if !ok {
+		c.errorIn(diag.NotCallable, cl.Callee, "cannot call value of type %s", ck)
+		return result{cl, nil}
+	}
+	if len(args) != len(fnT.Params) {
+		err := c.errorIn(diag.ArgumentCount, cl, "wrong number of arguments, expected %d got %d", len(fnT.Params), len(args))
+		c.callee(err, cl.Callee)
+		return result{cl, nil}
+	}
+	// an argument that failed to check was reported already and would
+	// leave its type parameters unbound
+	for _, k := range kinds {
+		if k == nil {
+			return result{cl, nil}
+		}
+	}
+	if fnT.TypeParams != nil {
+		bindings := make(map[string]ast.ValueKind)
+		for i, param := range fnT.Params {
+			unify(param.Kind, kinds[i], fnT.TypeParams, bindings)
+		}
+		for _, tp := range fnT.TypeParams {
+			if _, ok := bindings[tp.Name]; !ok {
+				c.errorIn(diag.CannotInfer, cl, "cannot infer %s in call to %s, instantiate it explicitly with %s[...]", tp.Name, callee, callee)
+				return result{cl, nil}
+			}
+		}
+		fnT, ok = c.instantiate(fnT, bindings, callee.String(), cl)
+		if !ok {
+			return result{cl, nil}
+		}
+	}
+	for i, param := range fnT.Params {
+		if !assignable(param.Kind, kinds[i]) {
+			err := c.errorIn(diag.MismatchedTypes, args[i], "cannot use %s value as %s in argument %d", kinds[i], param.Kind, i+1)
+			c.callee(err, cl.Callee)
+		}
+	}
+
+	return result{cl, fnT.Rtype}
+}
+
+// callee points err at the declaration of the fn called by callee
+func (c *Checker) callee(err *CheckErr, callee ast.Expr) {
+	if ident, ok := callee.(ast.Literal); ok && ident.Kind == token.IDENT {
+		if sym, _ := c.resolve(ident.Value); sym != nil {
+			c.declared(err, ident.Value, sym, "%s declared here", ident.Value)
+		}
+	}
+}
+
+func (c *Checker) VisitIfExpr(i ast.IfExpr) any {
+	return c.ifExpr(i, true)
+}
+
+// ifExpr checks an if, whose kind is the kind shared by all of its branches.
+// An if used as a value must have an else and branches of the same kind,
+// otherwise it is an if statement whose kind is nil when they differ
+func (c *Checker) ifExpr(i ast.IfExpr, asValue bool) result {
+	cond, ck := c.check(i.Condition)
+	c.condition(ck, cond)
+	body, kind := c.block(i.Body)
+	i.Condition, i.Body = cond, body
+
+	kinds := []ast.ValueKind{kind}
+	branches := [][]ast.Stmt{body}
+	var else_ifs []ast.IfExpr
+	for _, e := range i.Else_ifs {
+		econd, eck := c.check(e.Condition)
+		c.condition(eck, econd)
+		ebody, ekind := c.block(e.Body)
+		e.Condition, e.Body = econd, ebody
+		else_ifs = append(else_ifs, e)
+		kinds = append(kinds, ekind)
+		branches = append(branches, ebody)
+	}
+	i.Else_ifs = else_ifs
+
+	if i.Else == nil {
+		if asValue {
+			err := c.errorIn(diag.IfWithoutElse, i, "if used as a value must have an else branch")
+			err.help("add an else branch giving the value when the condition is false")
+			return result{i, nil}
+		}
+		return result{i, ast.NIL}
+	}
+	el, ekind := c.block(i.Else)
+	i.Else = el
+	kinds = append(kinds, ekind)
+	branches = append(branches, el)
+
+	for j, k := range kinds {
+		if k == nil {
+			return result{i, nil}
+		}
+		if !sameKind(k, kinds[0]) {
+			if asValue {
+				err := c.errorIn(diag.IfBranchTypes, i, "if used as a value has branches of different types %s and %s", kinds[0], k)
+				c.branch(err, branches[0], kinds[0])
+				c.branch(err, branches[j], k)
+				return result{i, nil}
+			}
+			return result{i, ast.NIL}
+		}
+	}
+	return result{i, kinds[0]}
+}
+
+// branch points err at the value of a branch of an if
+func (c *Checker) branch(err *CheckErr, body []ast.Stmt, kind ast.ValueKind) {
+	if len(body) == 0 {
+		return
+	}
+	if e, ok := body[len(body)-1].(ast.ExprStmt); ok {
+		start, end := c.file().Span(e.Expr)
+		err.label(start, end, "%s", kind)
+	}
+}
+
+func (c *Checker) VisitGet(g ast.Get) any {
+	ident, ok := g.Object.(ast.Literal)
+	if !ok || ident.Kind != token.IDENT {
+		c.errorIn(diag.ModuleAsValue, g.Object, "selector on value that is not a module")
+		return result{g, nil}
+	}
+	sym, _ := c.resolve(ident.Value)
+	if sym == nil {
+		c.errorIn(diag.Undefined, ident, "undefined: %s", ident.Value)
+		return result{g, nil}
+	}
+	if sym.module == nil {
+		err := c.errorIn(diag.ModuleAsValue, ident, "%s is not a module", ident.Value)
+		c.declared(err, ident.Value, sym, "declared here")
+		return result{g, nil}
+	}
+	kind, ok := sym.module.Exports[g.Name]
+	if !ok {
+		c.errorIn(diag.NotExported, g, "%s is not exported by module %s", g.Name, ident.Value)
+		return result{g, nil}
+	}
+
+	return result{g, kind}
+}
+
+func (c *Checker) VisitInstantiate(i ast.Instantiate) any {
+	fn, kind := c.check(i.Fn)
+	i.Fn = fn
+	if kind == nil {
+		return result{i, nil}
+	}
+
+	fnT, ok := kind.(ast.Fn)
+	if !ok || fnT.TypeParams == nil {
+		c.errorIn(diag.NotGeneric, i, "%s is not a generic fn", fn)
+		return result{i, nil}
+	}
+	if len(i.TypeArgs) != len(fnT.TypeParams) {
+		c.errorIn(diag.TypeArgumentCount, i, "wrong number of type arguments for %s, expected %d got %d", fn, len(fnT.TypeParams), len(i.TypeArgs))
+		return result{i, nil}
+	}
+
+	bindings := make(map[string]ast.ValueKind)
+	for j, tp := range fnT.TypeParams {
+		c.validKind(i.TypeArgs[j], i.Pos)
+		bindings[tp.Name] = i.TypeArgs[j]
+	}
+	fnT, ok = c.instantiate(fnT, bindings, fn.String(), i)
+	if !ok {
+		return result{i, nil}
+	}
+
+	return result{i, fnT}
+}
@@ -0,0 +1,307 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package checker_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/checker"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/parser"
+)
+
+// check parses and checks src, returning the checked tree and the codes of
+// the errors
+func check(t *testing.T, src string) (ast.Stmts, []diag.Code) {
+	t.Helper()
+	f, err := parser.ParseSource(context.Background(), "test.gr", src)
+	if err != nil {
+		t.Fatalf("parsing %q: %s", src, err)
+	}
+	c := checker.NewChecker(f.Lines, f.Name)
+	stmts, err := c.Check(f.Stmts)
+	if err == nil {
+		return stmts, nil
+	}
+	var derr diag.Error
+	if !errors.As(err, &derr) {
+		t.Fatalf("checking %q: %s", src, err)
+	}
+	var codes []diag.Code
+	for _, d := range derr.Diagnostics() {
+		codes = append(codes, d.Code)
+	}
+	return stmts, codes
+}
+
+// fns returns the fns of stmts in source order, enclosing fns before the
+// fns they contain
+func fns(stmts []ast.Stmt) []ast.FnExpr {
+	var found []ast.FnExpr
+	var expr func(e ast.Expr)
+	var stmt func(s ast.Stmt)
+	expr = func(e ast.Expr) {
+		switch e := e.(type) {
+		case ast.FnExpr:
+			found = append(found, e)
+			for _, s := range e.Body {
+				stmt(s)
+			}
+		case ast.Assignment:
+			expr(e.Value)
+		case ast.IfExpr:
+			for _, s := range e.Body {
+				stmt(s)
+			}
+			for _, elif := range e.Else_ifs {
+				expr(elif)
+			}
+			for _, s := range e.Else {
+				stmt(s)
+			}
+		}
+	}
+	stmt = func(s ast.Stmt) {
+		switch s := s.(type) {
+		case ast.ExprStmt:
+			expr(s.Expr)
+		case ast.VarDecl:
+			expr(s.Value)
+		case ast.WhileStmt:
+			for _, s := range s.Body {
+				stmt(s)
+			}
+		}
+	}
+	for _, s := range stmts {
+		stmt(s)
+	}
+	return found
+}
+
+func TestCaptures(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want are the captures of the last fn of src
+		want []ast.Upvalue
+	}{
+		{"global", "let g = 1\nfn f(): int g end", nil},
+		{"param", "fn adder(n: int): fn(int): int\n\tfn (x: int): int x + n end\nend", []ast.Upvalue{{Name: "n"}}},
+		{"mut", "fn counter(): fn(): int\n\tlet mut n = 0\n\tfn (): int\n\t\tn = n + 1\n\t\tn\n\tend\nend", []ast.Upvalue{{Name: "n", ByRef: true}}},
+		{"recursive", "fn outer(): int\n\tfn fact(n: int): int if n > 1 n * fact(n - 1) else 1 end end\n\tfact(5)\nend", []ast.Upvalue{{Name: "fact", ByRef: true}}},
+		{"nested", "fn a(x: int): fn(): fn(): int\n\tfn (): fn(): int\n\t\tfn (): int x end\n\tend\nend", []ast.Upvalue{{Name: "x"}}},
+		{"top level if", "let mut fs = fn (): int 0 end\nif 1\n\tlet k = 42\n\tfs = fn (): int k end\nend", []ast.Upvalue{{Name: "k"}}},
+		{"top level while", "let mut i = 0\nwhile i < 3\n\tlet j = i\n\tlet g = fn (): int j end\n\ti = i + 1\nend", []ast.Upvalue{{Name: "j"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts, codes := check(t, tt.src)
+			if codes != nil {
+				t.Fatalf("got errors %v", codes)
+			}
+			found := fns(stmts)
+			if len(found) == 0 {
+				t.Fatal("no fn found")
+			}
+			if got := found[len(found)-1].Captures; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("captures are %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedeclare(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []diag.Code
+	}{
+		{"global", "let x = 1\nfn f(): int x end\nlet x = \"a\"", []diag.Code{diag.Redeclared}},
+		{"builtin", "let len = 3", []diag.Code{diag.Redeclared}},
+		{"top level block", "if 1\n\tlet k = 1\n\tlet k = 2\nend", []diag.Code{diag.Redeclared}},
+		{"fn body", "fn f(): int\n\tlet a = 1\n\tlet a = 2\n\ta\nend", []diag.Code{diag.Redeclared}},
+		{"shadow", "let x = 1\nif 1\n\tlet x = \"a\"\nend", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := check(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []diag.Code
+	}{
+		{"fn", "let f = fn (n: int): int if n > 0 f(n - 1) else 0 end end", nil},
+		{"fn argument", "let f = fn (n: int): int if n > 0 f(\"x\") else n + 1 end end", []diag.Code{diag.MismatchedTypes}},
+		{"fn result", "let f = fn (n: int): int if n > 0 f(n - 1) + \"x\" else 0 end end", []diag.Code{diag.InvalidOperation}},
+		{"value", "let x = x + 1", []diag.Code{diag.UsedInInitializer}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := check(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []diag.Code
+	}{
+		{"bad operand", "println(1.5 % 2.0)", []diag.Code{diag.InvalidOperation}},
+		{"bad call", "println(split(\"a\", \",\"))", []diag.Code{diag.ArgumentCount}},
+		{"nil argument", "println(string(nil))", []diag.Code{diag.CannotInfer}},
+		{"constraint", "println(abs(\"x\"))", []diag.Code{diag.UnsatisfiedConstraint}},
+		{"instantiated", "let a = abs[string]", []diag.Code{diag.UnsatisfiedConstraint}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := check(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutualRecursion(t *testing.T) {
+	src := "fn even(n: int): int if n == 0 1 else odd(n - 1) end end\nfn odd(n: int): int if n == 0 0 else even(n - 1) end end\nprintln(even(10))"
+	if _, got := check(t, src); got != nil {
+		t.Errorf("got errors %v", got)
+	}
+	// only fn declarations are declared ahead
+	if _, got := check(t, "fn f(): int x end\nlet x = 1"); !reflect.DeepEqual(got, []diag.Code{diag.Undefined}) {
+		t.Errorf("got errors %v, want %v", got, []diag.Code{diag.Undefined})
+	}
+}
+
+func TestInputShadows(t *testing.T) {
+	c := checker.NewChecker(nil, "<stdin>")
+	inputs := []struct {
+		src  string
+		want []diag.Code
+	}{
+		{"let x = 1\nfn f(): int x end", nil},
+		{"let x = \"a\"", nil},
+		{"x + \"b\"", nil},
+		{"f() + 1", nil},
+		{"fn f(): string x end", nil},
+		{"let y = 1\nlet y = 2", []diag.Code{diag.Redeclared}},
+		{"let len = 1", []diag.Code{diag.Redeclared}},
+	}
+	for _, in := range inputs {
+		f, err := parser.ParseSource(context.Background(), "<stdin>", in.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []diag.Code
+		if _, _, err := c.CheckInput(f.Stmts, f.Lines, f.Name); err != nil {
+			for _, d := range err.(diag.Error).Diagnostics() {
+				got = append(got, d.Code)
+			}
+		}
+		if !reflect.DeepEqual(got, in.want) {
+			t.Errorf("%q: got errors %v, want %v", in.src, got, in.want)
+		}
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	decls := "fn max[T: numeric](a: T, b: T): T if a > b a else b end end\nfn eq[T: comparable](a: T, b: T): int a == b end\nfn id[T](x: T): T x end\n"
+	tests := []struct {
+		name string
+		src  string
+		want []diag.Code
+	}{
+		{"inferred", "let a: int = max(1, 2)\nlet b: float = max(1.5, 0.5)\nlet c: int = eq(\"a\", \"b\")", nil},
+		{"inferred result", "max(1, 2) + \"s\"", []diag.Code{diag.InvalidOperation}},
+		{"inferred mismatch", "max(1, 2.5)", []diag.Code{diag.MismatchedTypes}},
+		{"explicit", "let a: float = max[float](1.0, 2.5)\nlet m = max[int]\nlet b: int = m(3, 4)\nlet s: string = id[string](nil)", nil},
+		{"explicit mismatch", "let m = max[int]\nm(1.5, 2)", []diag.Code{diag.MismatchedTypes}},
+		{"numeric", "max(\"a\", \"b\")", []diag.Code{diag.UnsatisfiedConstraint}},
+		{"numeric explicit", "let m = max[string]", []diag.Code{diag.UnsatisfiedConstraint}},
+		{"comparable", "eq(fn (): int 1 end, fn (): int 2 end)", []diag.Code{diag.UnsatisfiedConstraint}},
+		{"cannot infer", "id(nil)", []diag.Code{diag.CannotInfer}},
+		{"type argument count", "let m = max[int, float]", []diag.Code{diag.TypeArgumentCount}},
+		{"not generic", "fn g(): int 1 end\nlet h = g[int]", []diag.Code{diag.NotGeneric}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := check(t, decls+tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntOperators(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []diag.Code
+	}{
+		{"let a: int = 7 % 3 + (1 << 4 | 3 ^ 5 & 6 >> 1) + ~0", nil},
+		{"1.5 % 2.0", []diag.Code{diag.InvalidOperation}},
+		{"\"a\" | \"b\"", []diag.Code{diag.InvalidOperation}},
+		{"1 & 2.0", []diag.Code{diag.InvalidOperation}},
+		{"1 << 2.0", []diag.Code{diag.InvalidOperation}},
+		{"~1.5", []diag.Code{diag.InvalidOperation}},
+		{"let mut s = \"a\"\ns += \"b\"\ns %= \"c\"", []diag.Code{diag.InvalidOperation}},
+		{"let x = 1\nx += 1", []diag.Code{diag.AssignToImmutable}},
+	}
+	for _, tt := range tests {
+		if _, got := check(t, tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got errors %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestInferKind(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"let x = 1", "(let x int 1)"},
+		{"let mut x = 1.5", "(let mut x float 1.5)"},
+		{"let x = \"s\"", "(let x string \"s\")"},
+		{"let x = 1 < 2", "(let x int (< 1 2))"},
+		{"let x: float = 2.0", "(let x float 2.0)"},
+		{"let x: int = nil", "(let x int nil)"},
+	}
+	for _, tt := range tests {
+		stmts, codes := check(t, tt.src)
+		if codes != nil {
+			t.Fatalf("%q: got errors %v", tt.src, codes)
+		}
+		if got := stmts[0].String(); got != tt.want {
+			t.Errorf("%q checked to %s, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{"let x = nil", "let mut x = nil", "fn f(): int\n\tlet x = nil\n\t1\nend"} {
+		if _, got := check(t, src); !reflect.DeepEqual(got, []diag.Code{diag.NilInference}) {
+			t.Errorf("%q: got errors %v, want %v", src, got, []diag.Code{diag.NilInference})
+		}
+	}
+}
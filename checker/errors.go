@@ -0,0 +1,93 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package checker
+
+import (
+	"fmt"
+	"strings"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
+)
+
+type CheckError []error
+
+func (c CheckError) Diagnostics() []diag.Diagnostic {
+	return diag.Collect(c)
+}
+
+func (c CheckError) Error() string {
+	var str strings.Builder
+
+	for _, err := range c {
+		fmt.Fprintln(&str, err.Error())
+	}
+
+	return str.String()
+}
+
+type CheckErr struct {
+	code diag.Code
+	msg  string
+	// labels are the spans the error is about, the first is where it is
+	labels []diag.Label
+	notes  []diag.Note
+	lines  []string
+	fname  string
+}
+
+func (c CheckErr) Error() string {
+	return c.diagnostic().String()
+}
+
+func (c CheckErr) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{c.diagnostic()}
+}
+
+func (c CheckErr) diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity: "error",
+		Code:     c.code,
+		Msg:      c.msg,
+		File:     c.fname,
+		Lines:    c.lines,
+		Labels:   c.labels,
+		Notes:    c.notes,
+	}
+}
+
+// label points at the source from start up to end with msg
+func (c *CheckErr) label(start ast.Pos, end ast.Pos, format string, args ...any) {
+	c.labels = append(c.labels, diag.Label{
+		Span: diag.Span{Line: start.Line, Col: start.Col, EndLine: end.Line, EndCol: end.Col},
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *CheckErr) help(format string, args ...any) {
+	c.notes = append(c.notes, diag.Note{Kind: "help", Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *Checker) newCheckErr(code diag.Code, start ast.Pos, end ast.Pos, format string, args ...any) *CheckErr {
+	msg := fmt.Sprintf(format, args...)
+	return &CheckErr{
+		code: code,
+		msg:  msg,
+		labels: []diag.Label{{
+			Span:    diag.Span{Line: start.Line, Col: start.Col, EndLine: end.Line, EndCol: end.Col},
+			Msg:     msg,
+			Primary: true,
+		}},
+		lines: c.lines,
+		fname: c.fname,
+	}
+}
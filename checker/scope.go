@@ -0,0 +1,120 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package checker
+
+import (
+	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
+)
+
+type symbol struct {
+	kind    ast.ValueKind
+	mut     bool
+	pub     bool
+	ready   bool
+	builtin bool
+	module  *Module
+	// decl is where the symbol is declared in the input numbered input
+	decl  ast.Pos
+	input int
+}
+
+// Module is the public interface of a checked file
+type Module struct {
+	Path    string
+	Exports map[string]ast.ValueKind
+}
+
+type function struct {
+	rtype     ast.ValueKind
+	pos       ast.Pos
+	captures  []ast.Upvalue
+	enclosing *function
+}
+
+func (f *function) capture(name string, byRef bool) {
+	for i, u := range f.captures {
+		if u.Name == name {
+			f.captures[i].ByRef = u.ByRef || byRef
+			return
+		}
+	}
+	f.captures = append(f.captures, ast.Upvalue{Name: name, ByRef: byRef})
+}
+
+// scope is a single block of bindings, fn is the function the block
+// belongs to and is nil for the global scope and the blocks at the top level
+type scope struct {
+	symbols map[string]*symbol
+	fn      *function
+}
+
+func newScope(fn *function) *scope {
+	return &scope{
+		symbols: make(map[string]*symbol),
+		fn:      fn,
+	}
+}
+
+func (c *Checker) beginScope() {
+	c.scopes = append(c.scopes, newScope(c.fn))
+}
+
+func (c *Checker) endScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// declare binds name in the innermost scope. Globals are looked up by name
+// when a fn runs, so redeclaring one, builtins included, would change the
+// kind of a binding under the fns already checked against it. An input of
+// an interactive session can shadow the globals of the inputs before it,
+// which the interpreter keeps for the fns declared by those inputs
+func (c *Checker) declare(name string, sym *symbol, pos ast.Pos) {
+	s := c.scopes[len(c.scopes)-1]
+	if prev, ok := s.symbols[name]; ok && !(len(c.scopes) == 1 && !prev.builtin && prev.input != c.input) {
+		if prev.builtin {
+			c.error(diag.Redeclared, pos, "%s is a builtin and cannot be redeclared", name)
+		} else {
+			err := c.error(diag.Redeclared, pos, "%s is already declared in this scope", name)
+			c.declared(err, name, prev, "previous declaration here")
+		}
+	}
+	s.symbols[name] = sym
+}
+
+// resolve looks name up from the innermost scope outwards. Locals of an
+// enclosing function become upvalues of every function between the
+// reference and the declaration, mutable bindings and bindings that are
+// still being initialized (a fn referring to itself) are captured by
+// reference. sameFn reports whether the binding belongs to the function
+// currently being checked.
+func (c *Checker) resolve(name string) (sym *symbol, sameFn bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		s := c.scopes[i]
+		sym, ok := s.symbols[name]
+		if !ok {
+			continue
+		}
+		// only the globals are not captured, the blocks at the top
+		// level have a nil fn too
+		if s.fn != c.fn && i != 0 {
+			byRef := sym.mut || !sym.ready
+			for f := c.fn; f != s.fn; f = f.enclosing {
+				f.capture(name, byRef)
+			}
+		}
+		return sym, s.fn == c.fn
+	}
+
+	return nil, false
+}
@@ -0,0 +1,122 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package checker
+
+import (
+	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
+)
+
+type stmtResult struct {
+	stmt ast.Stmt
+	kind ast.ValueKind
+}
+
+// checkStmt checks stmt, an expression statement has the kind of its
+// expression and every other statement is nil
+func (c *Checker) checkStmt(stmt ast.Stmt) (ast.Stmt, ast.ValueKind) {
+	r := stmt.Accept(c).(stmtResult)
+	return r.stmt, r.kind
+}
+
+func (c *Checker) VisitExprStmt(e ast.ExprStmt) any {
+	var r result
+	if i, ok := e.Expr.(ast.IfExpr); ok {
+		r = c.ifExpr(i, false)
+	} else {
+		r = e.Expr.Accept(c).(result)
+	}
+	e.Expr = r.expr
+
+	return stmtResult{e, r.kind}
+}
+
+func (c *Checker) VisitVarDecl(v ast.VarDecl) any {
+	valid := c.validKind(v.Kind, v.Pos)
+	sym, hoisted := c.hoisted[v.Pos]
+	if hoisted && len(c.scopes) == 1 {
+		delete(c.hoisted, v.Pos)
+	} else {
+		sym = &symbol{kind: v.Kind, mut: v.IsMut(), pub: v.IsPub(), decl: v.Pos, input: c.input}
+		if f, ok := v.Value.(ast.FnExpr); ok && v.Kind == nil {
+			// a fn can refer to itself in its body, which is checked
+			// against its signature
+			sym.kind = signature(f)
+		}
+		c.declare(v.Name, sym, v.Pos)
+	}
+	value, kind := c.check(v.Value)
+	sym.ready = true
+	v.Value = value
+
+	if v.Kind == nil {
+		if kind == ast.NIL {
+			start, end := nameSpan(v.Pos, v.Name)
+			c.errorSpan(diag.NilInference, start, end, "cannot infer the type of %s from nil, add a type annotation", v.Name)
+		} else {
+			v.Kind = kind
+			sym.kind = kind
+		}
+	} else if valid && !assignable(v.Kind, kind) {
+		err := c.errorIn(diag.MismatchedTypes, v.Value, "cannot use %s value as %s in declaration of %s", kind, v.Kind, v.Name)
+		c.declared(err, v.Name, sym, "declared as %s here", v.Kind)
+	}
+
+	return stmtResult{v, ast.NIL}
+}
+
+func (c *Checker) VisitWhileStmt(w ast.WhileStmt) any {
+	cond, ck := c.check(w.Cond)
+	c.condition(ck, cond)
+	body, _ := c.block(w.Body)
+	w.Cond, w.Body = cond, body
+
+	return stmtResult{w, ast.NIL}
+}
+
+func (c *Checker) VisitReturnStmt(r ast.Return) any {
+	value, kind := c.check(r.Value)
+	r.Value = value
+
+	if c.fn == nil {
+		start, end := nameSpan(r.Pos, "return")
+		c.errorSpan(diag.ReturnOutsideFn, start, end, "return outside of fn")
+	} else if !assignable(c.fn.rtype, kind) {
+		err := c.errorIn(diag.MismatchedTypes, r.Value, "cannot return %s value from fn returning %s", kind, c.fn.rtype)
+		start, end := nameSpan(c.fn.pos, "fn")
+		err.label(start, end, "returns %s", c.fn.rtype)
+	}
+
+	return stmtResult{r, ast.NIL}
+}
+
+func (c *Checker) VisitImport(i ast.Import) any {
+	m, ok := c.modules[i.Path]
+	if !ok {
+		c.error(diag.ModuleNotFound, i.Pos, "module %q not found", i.Path)
+		return stmtResult{i, ast.NIL}
+	}
+	c.declare(i.Name(), &symbol{ready: true, module: &m}, i.Pos)
+
+	return stmtResult{i, ast.NIL}
+}
+
+// signature returns the kind of f without its parameter names, as fn
+// declarations are annotated by the parser
+func signature(f ast.FnExpr) ast.Fn {
+	params := []ast.Param{}
+	for _, p := range f.Params {
+		params = append(params, ast.NewParam("", p.Kind))
+	}
+	return ast.NewFnT(f.TypeParams, params, f.Rtype)
+}
@@ -14,10 +14,8 @@ package cmd
 
 import (
 	"fmt"
-	"io/ioutil"
-	"zimlit/graphene/ast"
-	"zimlit/graphene/lexer"
-	"zimlit/graphene/parser"
+	"os"
+	"zimlit/graphene/loader"
 
 	"github.com/spf13/cobra"
 )
@@ -34,32 +32,21 @@ var buildCmd = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		for _, arg := range args {
-			buf, err := ioutil.ReadFile(arg)
+			root, path, err := loader.Project(arg)
 			if err != nil {
-				fmt.Println(err)
+				printErr(os.Stdout, err)
 				return
 			}
-			source := string(buf)
-			l := lexer.NewLexer(source, arg)
-			toks, lines, errs := l.Lex()
-			if errs != nil {
-				fmt.Print(errs.Error())
-			} else {
-				p := parser.NewParser(toks, lines, arg)
-				c := make(chan parser.ParseResult)
-				go p.Parse(c)
-				exprs := [][]ast.Expr{}
-				parse_res := <-c
-				if parse_res.Err != nil {
-					fmt.Println(parse_res.Err.Error())
-				} else {
-					exprs = append(exprs, parse_res.Exprs)
-				}
-				fmt.Printf("%s:\n", arg)
-				for _, x := range exprs {
-					for _, expr := range x {
-						fmt.Println(expr.String())
-					}
+			l := loader.NewLoader(root)
+			_, err = l.Load(path)
+			if err != nil {
+				printErr(os.Stdout, err)
+				continue
+			}
+			for _, m := range l.Modules() {
+				fmt.Printf("%s:\n", m.File)
+				for _, stmt := range m.Stmts {
+					fmt.Println(stmt.String())
 				}
 				fmt.Println()
 			}
@@ -0,0 +1,77 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var format string
+
+// parseCmd represents the parse command
+var parseCmd = &cobra.Command{
+	Use:   "parse [file]",
+	Short: "Parses the files passed in [file] and prints their syntax trees",
+	Long: `Parses the files passed in [file] and prints their syntax trees.
+
+--format=sexpr prints every statement as an s-expression, --format=json
+prints each file as a JSON document, see ast.JSONVersion for the schema.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if format != "sexpr" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unknown format %q, expected sexpr or json\n", format)
+			os.Exit(1)
+		}
+		failed := false
+		for _, arg := range args {
+			f, err := parser.ParseFile(context.Background(), arg)
+			if err != nil {
+				printErr(os.Stderr, err)
+				failed = true
+				continue
+			}
+
+			if format == "sexpr" {
+				for _, stmt := range f.Stmts {
+					fmt.Println(stmt.String())
+				}
+				continue
+			}
+			buf, err := ast.MarshalJSON(f)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				continue
+			}
+			var out bytes.Buffer
+			json.Indent(&out, buf, "", "  ")
+			fmt.Println(out.String())
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(parseCmd)
+	parseCmd.Flags().StringVar(&format, "format", "sexpr", "output format, sexpr or json")
+}
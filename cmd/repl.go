@@ -0,0 +1,120 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/parser"
+	"zimlit/graphene/repl"
+
+	"github.com/chzyer/readline"
+)
+
+const historyFile = ".graphene_history"
+
+func runREPL() {
+	config := &readline.Config{Prompt: "> "}
+	if home, err := os.UserHomeDir(); err == nil {
+		config.HistoryFile = filepath.Join(home, historyFile)
+	}
+	session := repl.NewSession("stdin")
+	config.AutoComplete = completer{session}
+
+	rl, err := readline.NewEx(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rl.Close()
+
+	session.SetHost(&builtins.Host{Stdout: rl.Stdout()})
+	ctx := context.Background()
+	for {
+		rl.SetPrompt(session.Prompt())
+		line, err := rl.Readline()
+		// an interrupt discards the lines of an incomplete input
+		if err == readline.ErrInterrupt && session.Pending() {
+			session.Discard()
+			continue
+		}
+		if err != nil {
+			break
+		}
+
+		if !session.Pending() && repl.IsCommand(line) {
+			err := session.Command(ctx, line, rl.Stdout())
+			if err == repl.ErrQuit {
+				break
+			}
+			replErr(rl, err)
+			continue
+		}
+
+		result, err := session.Line(ctx, line)
+		if parser.Incomplete(err) {
+			continue
+		}
+		replErr(rl, err)
+		if err == nil && result.Show {
+			fmt.Fprintln(rl.Stdout(), result)
+		}
+	}
+}
+
+// replErr prints err, a call to exit leaves the REPL with its status
+func replErr(rl *readline.Instance, err error) {
+	if exit, ok := err.(builtins.Exit); ok {
+		rl.Close()
+		os.Exit(exit.Code)
+	}
+	if err == nil {
+		return
+	}
+	var msg strings.Builder
+	diag.Fprint(&msg, renderer(os.Stderr), err)
+	io.WriteString(rl.Stderr(), msg.String())
+}
+
+// completer completes the word before the cursor with the keywords, the
+// names bound in the session and the meta commands
+type completer struct {
+	session *repl.Session
+}
+
+func (c completer) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && (unicode.IsLetter(line[start-1]) || unicode.IsDigit(line[start-1]) || line[start-1] == '_') {
+		start--
+	}
+	if start == 1 && line[0] == ':' {
+		start = 0
+	}
+	prefix := string(line[start:pos])
+	if prefix == "" {
+		return nil, 0
+	}
+
+	var candidates [][]rune
+	for _, word := range c.session.Complete(prefix) {
+		candidates = append(candidates, []rune(word[len(prefix):]))
+	}
+	return candidates, len([]rune(prefix))
+}
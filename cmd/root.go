@@ -13,17 +13,15 @@
 package cmd
 
 import (
-	"fmt"
-	"log"
 	"os"
-	"zimlit/graphene/ast"
-	"zimlit/graphene/lexer"
-	"zimlit/graphene/parser"
+	"zimlit/graphene/diag"
 
-	"github.com/chzyer/readline"
 	"github.com/spf13/cobra"
 )
 
+// colorMode is the --color flag, one of the modes of diag.UseColor
+var colorMode string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "graphene",
@@ -32,40 +30,24 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
-		rl, err := readline.New("> ")
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer rl.Close()
-
-		for {
-			line, err := rl.Readline()
-			if err != nil {
-				break
-			}
-			l := lexer.NewLexer(line, "stdin")
-			toks, lines, errs := l.Lex()
-			if errs != nil {
-				fmt.Print(errs.Error())
-			} else {
-				p := parser.NewParser(toks, lines, "stdin")
-				c := make(chan parser.ParseResult)
-				go p.Parse(c)
-				exprs := [][]ast.Expr{}
-				parse_res := <-c
-				if parse_res.Err != nil {
-					fmt.Println(parse_res.Err.Error())
-				} else {
-					exprs = append(exprs, parse_res.Exprs)
-				}
-				for _, x := range exprs {
-					for _, expr := range x {
-						fmt.Println(expr.String())
-					}
-				}
-			}
-		}
+		runREPL()
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := diag.UseColor(colorMode, nil)
+		return err
+	},
+}
+
+// renderer returns the renderer of diagnostics written to f, which are
+// colored as the --color flag asks
+func renderer(f *os.File) diag.Renderer {
+	color, _ := diag.UseColor(colorMode, f)
+	return diag.Text{Color: color}
+}
+
+// printErr writes err to f
+func printErr(f *os.File, err error) {
+	diag.Fprint(f, renderer(f), err)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -83,6 +65,7 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.graphene.yaml)")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", diag.ColorAuto, "color diagnostics: auto, always or never, auto follows NO_COLOR and CLICOLOR_FORCE")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
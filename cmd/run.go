@@ -0,0 +1,85 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"io"
+	"os"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/interp"
+	"zimlit/graphene/loader"
+
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [path] [args...]",
+	Short: "Runs the file or project passed in [path]",
+	Long: `Runs the file or project passed in [path], a directory is a project whose
+entry module is main. The remaining arguments are passed to the program and
+can be read with nargs and arg.
+
+The exit status is the one passed to exit, 0 if the program finishes, 1 if it
+does not compile and 2 if it stops with a runtime error.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(run(args[0], args[1:], os.Stdout, os.Stderr, renderer(os.Stderr)))
+	},
+}
+
+// run loads the program at path and runs its modules, each one after the
+// modules it imports, returning the exit status of the program. The program
+// writes to stdout and its diagnostics are rendered with r to stderr
+func run(path string, args []string, stdout io.Writer, stderr io.Writer, r diag.Renderer) int {
+	root, entry, err := loader.Project(path)
+	if err != nil {
+		diag.Fprint(stderr, r, err)
+		return 1
+	}
+	l := loader.NewLoader(root)
+	if _, err := l.Load(entry); err != nil {
+		diag.Fprint(stderr, r, err)
+		return 1
+	}
+
+	host := &builtins.Host{Stdout: stdout, Args: args}
+	modules := make(map[string]*interp.Module)
+	for _, m := range l.Modules() {
+		in := interp.NewInterpreter(m.Lines, m.File)
+		in.SetHost(host)
+		for _, stmt := range m.Stmts {
+			if imp, ok := stmt.(ast.Import); ok {
+				in.AddModule(modules[imp.Path])
+			}
+		}
+
+		_, err := in.Interpret(m.Stmts)
+		if exit, ok := err.(builtins.Exit); ok {
+			return exit.Code
+		}
+		if err != nil {
+			diag.Fprint(stderr, r, err)
+			return 2
+		}
+		modules[m.Path] = in.Module(m.Path)
+	}
+	return 0
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	// flags after [path] belong to the program
+	runCmd.Flags().SetInterspersed(false)
+}
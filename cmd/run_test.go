@@ -0,0 +1,124 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zimlit/graphene/diag"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to a new directory, the program is main.gr
+		// unless it is the directory itself
+		files  map[string]string
+		dir    bool
+		args   []string
+		status int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "finishes",
+			files:  map[string]string{"main.gr": "println(1)\n"},
+			stdout: "1\n",
+		},
+		{
+			name:   "args",
+			files:  map[string]string{"main.gr": "println(nargs())\nprintln(arg(0))\nprintln(arg(1))\n"},
+			args:   []string{"a", "-b"},
+			stdout: "2\na\n-b\n",
+		},
+		{
+			name:   "exit",
+			files:  map[string]string{"main.gr": "println(\"before\")\nexit(3)\nprintln(\"after\")\n"},
+			status: 3,
+			stdout: "before\n",
+		},
+		{
+			name:   "exit in fn",
+			files:  map[string]string{"main.gr": "fn f(): int\n\texit(0)\n\t1\nend\nf()\nprintln(\"after\")\n"},
+			status: 0,
+		},
+		{
+			name:   "exit in imported module",
+			files:  map[string]string{"main.gr": "import \"lib\"\nprintln(\"main\")\n", "lib.gr": "exit(4)\n"},
+			dir:    true,
+			status: 4,
+		},
+		{
+			name:   "compile error",
+			files:  map[string]string{"main.gr": "println(1)\nlet x: int = \"s\"\n"},
+			status: 1,
+			stderr: "error[E0017]",
+		},
+		{
+			name:   "syntax error",
+			files:  map[string]string{"main.gr": "println(\n"},
+			status: 1,
+			stderr: "error[",
+		},
+		{
+			name:   "missing",
+			files:  map[string]string{},
+			status: 1,
+		},
+		{
+			name:   "runtime error",
+			files:  map[string]string{"main.gr": "println(1)\nlet z = 0\nprintln(1 / z)\n"},
+			status: 2,
+			stdout: "1\n",
+			stderr: "integer division by zero",
+		},
+		{
+			name:   "project",
+			files:  map[string]string{"main.gr": "import \"lib\"\nprintln(lib.two)\n", "lib.gr": "pub let two: int = 2\n"},
+			dir:    true,
+			stdout: "2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, src := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(dir, "main.gr")
+			if tt.dir {
+				path = dir
+			}
+
+			var stdout, stderr strings.Builder
+			status := run(path, tt.args, &stdout, &stderr, diag.Text{})
+			if status != tt.status {
+				t.Errorf("exit status %d, want %d, stderr:\n%s", status, tt.status, stderr.String())
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("printed %q, want %q", stdout.String(), tt.stdout)
+			}
+			if tt.status == 0 || tt.status > 2 {
+				if stderr.Len() != 0 {
+					t.Errorf("unexpected diagnostics:\n%s", stderr.String())
+				}
+			} else if stderr.Len() == 0 || !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("diagnostics %q, want %q", stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package cst is a lossless token layer over graphene source. Every token
+// keeps the text it was lexed from and the whitespace around it, so a tree
+// prints back to exactly its source.
+//
+// The tree is flat, it is the list of tokens grouped by top level statement
+// and does not nest nodes below a statement. The ast is not lowered from it,
+// the parser builds the ast from the same tokens and Parse pairs each
+// statement with the tokens it was parsed from
+package cst
+
+import (
+	"context"
+	"io"
+	"strings"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/lexer"
+	"zimlit/graphene/parser"
+	"zimlit/graphene/token"
+)
+
+// Token is a token along with its source text and trivia. Trailing trivia
+// is the whitespace after the token up to the end of its line, leading
+// trivia is the rest of the whitespace before it. A newline that ends a
+// statement is itself a semicolon token whose text is the newline
+type Token struct {
+	token.Token
+	Leading  string
+	Text     string
+	Trailing string
+}
+
+// Node is a top level statement and the tokens it was parsed from,
+// including its terminator
+type Node struct {
+	Stmt   ast.Stmt
+	Tokens []Token
+}
+
+// Tree is the tokens of a file along with the ast parsed from them
+type Tree struct {
+	// File is the ast the parser built from Tokens
+	File   ast.File
+	Tokens []Token
+	Nodes  []Node
+	// EOF is the trivia after the last token
+	EOF string
+}
+
+// Parse lexes and parses src into a tree, name is the file name used in
+// error messages
+func Parse(ctx context.Context, name string, src string) (*Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l := lexer.NewLexer(src, name)
+	toks, lines, lexErrs := l.Lex()
+
+	p := parser.NewParser(toks, lines, name)
+	file, starts, err := p.ParseStarts()
+	if err = parser.JoinErrors(lexErrs, err); err != nil {
+		return nil, err
+	}
+
+	// spans count runes, the text of the tokens is sliced from src so that
+	// invalid UTF-8 is kept as it is
+	offsets := make([]int, 0, len(src)+1)
+	for i := range src {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(src))
+
+	tree := &Tree{File: file}
+	prev := 0
+	for i, span := range l.Spans() {
+		start, end := offsets[span.Start], offsets[span.End]
+		tree.Tokens = append(tree.Tokens, Token{
+			Token:   toks[i],
+			Leading: src[prev:start],
+			Text:    src[start:end],
+		})
+		prev = end
+	}
+	tree.EOF = src[prev:]
+	for i := range tree.Tokens {
+		next := &tree.EOF
+		if i+1 < len(tree.Tokens) {
+			next = &tree.Tokens[i+1].Leading
+		}
+		tree.Tokens[i].Trailing, *next = splitTrivia(*next)
+	}
+
+	for i, start := range starts {
+		end := len(tree.Tokens)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		tree.Nodes = append(tree.Nodes, Node{file.Stmts[i], tree.Tokens[start:end]})
+	}
+	return tree, nil
+}
+
+// splitTrivia splits the trivia between two tokens into the trailing trivia
+// of the first one, which stops before a newline, and the leading trivia of
+// the second one
+func splitTrivia(trivia string) (trailing string, leading string) {
+	if i := strings.IndexByte(trivia, '\n'); i >= 0 {
+		return trivia[:i], trivia[i:]
+	}
+	return trivia, ""
+}
+
+// Fprint writes the source of t to w
+func Fprint(w io.Writer, t *Tree) error {
+	for _, tok := range t.Tokens {
+		if _, err := io.WriteString(w, tok.Leading+tok.Text+tok.Trailing); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, t.EOF)
+	return err
+}
+
+// Print returns the source of t
+func Print(t *Tree) string {
+	var str strings.Builder
+	Fprint(&str, t)
+	return str.String()
+}
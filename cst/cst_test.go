@@ -0,0 +1,81 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package cst
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/parser"
+)
+
+// seed adds the parser test sources to the corpus of f
+func seed(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*.gr"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(buf))
+	}
+	f.Add("let x = 1  \r\n\n\t x + 2 ;  ; \n")
+	f.Add("if x\t1 else  if y 2 end   ")
+	f.Add("\"a\\\"b\" \n")
+}
+
+// FuzzPrint checks that a tree prints back to its source and that its ast is
+// the one the parser derives from the source
+func FuzzPrint(f *testing.F) {
+	seed(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		ctx := context.Background()
+		tree, err := Parse(ctx, "fuzz.gr", src)
+		file, perr := parser.ParseSource(ctx, "fuzz.gr", src)
+		if (err == nil) != (perr == nil) {
+			t.Fatalf("Parse error %v, ParseSource error %v", err, perr)
+		}
+		if err != nil {
+			return
+		}
+
+		if got := Print(tree); got != src {
+			t.Fatalf("printed %q, want %q", got, src)
+		}
+		got, _ := ast.MarshalJSON(tree.File)
+		want, _ := ast.MarshalJSON(file)
+		if string(got) != string(want) {
+			t.Fatalf("ast %s, want %s", got, want)
+		}
+		if len(tree.Nodes) != len(tree.File.Stmts) {
+			t.Fatalf("%d nodes for %d statements", len(tree.Nodes), len(tree.File.Stmts))
+		}
+		for _, n := range tree.Nodes {
+			if len(n.Tokens) == 0 {
+				t.Fatalf("node %s has no tokens", n.Stmt)
+			}
+		}
+		for _, tok := range tree.Tokens {
+			for _, c := range tok.Leading + tok.Trailing {
+				if c != ' ' && c != '\t' && c != '\r' && c != '\v' && c != '\n' {
+					t.Fatalf("trivia %q of %q is not whitespace", tok.Leading+tok.Trailing, tok.Text)
+				}
+			}
+		}
+	})
+}
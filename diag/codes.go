@@ -0,0 +1,106 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Code identifies a kind of error, it is stable across releases and is
+// explained at length by graphene explain
+type Code string
+
+// Codes of the lexer
+const (
+	UnexpectedCharacter Code = "E0001"
+	UnclosedString      Code = "E0002"
+	InvalidEscape       Code = "E0003"
+	MalformedNumber     Code = "E0004"
+)
+
+// Codes of the parser
+const (
+	UnexpectedToken    Code = "E0005"
+	ExpectedExpression Code = "E0006"
+	MissingSeparator   Code = "E0007"
+	InvalidAssignment  Code = "E0008"
+	UnknownConstraint  Code = "E0009"
+	StatementAsValue   Code = "E0010"
+	NamedFnAsValue     Code = "E0011"
+	NotTopLevel        Code = "E0012"
+	ReturnOutsideFn    Code = "E0013"
+	PubNotNamed        Code = "E0014"
+)
+
+// Codes of the checker and the loader
+const (
+	Undefined             Code = "E0015"
+	UndefinedType         Code = "E0016"
+	MismatchedTypes       Code = "E0017"
+	InvalidOperation      Code = "E0018"
+	NonIntCondition       Code = "E0019"
+	AssignToImmutable     Code = "E0020"
+	NotCallable           Code = "E0021"
+	ArgumentCount         Code = "E0022"
+	CannotInfer           Code = "E0023"
+	UnsatisfiedConstraint Code = "E0024"
+	IfWithoutElse         Code = "E0025"
+	IfBranchTypes         Code = "E0026"
+	ModuleAsValue         Code = "E0027"
+	NotExported           Code = "E0028"
+	NotGeneric            Code = "E0029"
+	TypeArgumentCount     Code = "E0030"
+	UsedInInitializer     Code = "E0031"
+	Redeclared            Code = "E0032"
+	NilInference          Code = "E0033"
+	ModuleNotFound        Code = "E0034"
+	ImportCycle           Code = "E0035"
+)
+
+//go:embed explain/*.md
+var explanations embed.FS
+
+// Explain returns the explanation of code as markdown
+func Explain(code Code) (string, error) {
+	buf, err := explanations.ReadFile("explain/" + strings.ToUpper(string(code)) + ".md")
+	if err != nil {
+		return "", fmt.Errorf("unknown error code %s", code)
+	}
+	return string(buf), nil
+}
+
+// Codes returns every code that has an explanation, sorted
+func Codes() []Code {
+	entries, _ := fs.ReadDir(explanations, "explain")
+	var codes []Code
+	for _, e := range entries {
+		codes = append(codes, Code(strings.TrimSuffix(e.Name(), ".md")))
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Title returns the first line of the explanation of code without its
+// heading marks
+func Title(code Code) string {
+	text, err := Explain(code)
+	if err != nil {
+		return ""
+	}
+	title, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(strings.TrimLeft(title, "#"))
+}
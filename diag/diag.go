@@ -0,0 +1,161 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package diag holds what the diagnostic renderers of every package share
+package diag
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TabWidth is the distance between the tab stops of rendered source lines
+const TabWidth = 4
+
+// ExpandTabs replaces the tabs of line with spaces up to the next tab stop,
+// so the line is drawn the same wherever it starts on the screen
+func ExpandTabs(line string) string {
+	if !strings.ContainsRune(line, '\t') {
+		return line
+	}
+	var str strings.Builder
+	width := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := TabWidth - width%TabWidth
+			str.WriteString(strings.Repeat(" ", n))
+			width += n
+			continue
+		}
+		str.WriteRune(r)
+		width += RuneWidth(r)
+	}
+	return str.String()
+}
+
+// Column returns the display column of the rune at col of line, counting
+// from 1. Tabs expand to the next tab stop, wide characters take two
+// columns and combining marks none. A col past the end of the line is as
+// many columns past the end
+func Column(line string, col int) int {
+	width, n := 0, 1
+	for _, r := range strings.TrimSuffix(line, "\n") {
+		if n == col {
+			break
+		}
+		if r == '\t' {
+			width += TabWidth - width%TabWidth
+		} else {
+			width += RuneWidth(r)
+		}
+		n++
+	}
+	if col > n {
+		width += col - n
+	}
+	return width + 1
+}
+
+// RuneWidth returns the number of columns r takes in a terminal
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0x200b || r == 0x200d || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.IsControl(r):
+		return 0
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// wide are the ranges of the East Asian Wide and Fullwidth characters
+var wide = []struct{ lo, hi rune }{
+	{0x1100, 0x115f},   // Hangul Jamo initial consonants
+	{0x231a, 0x231b},   // watch, hourglass
+	{0x2329, 0x232a},   // angle brackets
+	{0x23e9, 0x23ec},   // media controls
+	{0x23f0, 0x23f0},   // alarm clock
+	{0x23f3, 0x23f3},   // hourglass with flowing sand
+	{0x25fd, 0x25fe},   // small squares
+	{0x2614, 0x2615},   // umbrella, hot beverage
+	{0x2648, 0x2653},   // zodiac
+	{0x267f, 0x267f},   // wheelchair
+	{0x2693, 0x2693},   // anchor
+	{0x26a1, 0x26a1},   // high voltage
+	{0x26aa, 0x26ab},   // circles
+	{0x26bd, 0x26be},   // balls
+	{0x26c4, 0x26c5},   // snowman, sun behind cloud
+	{0x26ce, 0x26ce},   // ophiuchus
+	{0x26d4, 0x26d4},   // no entry
+	{0x26ea, 0x26ea},   // church
+	{0x26f2, 0x26f3},   // fountain, flag in hole
+	{0x26f5, 0x26f5},   // sailboat
+	{0x26fa, 0x26fa},   // tent
+	{0x26fd, 0x26fd},   // fuel pump
+	{0x2705, 0x2705},   // check mark
+	{0x270a, 0x270b},   // fists
+	{0x2728, 0x2728},   // sparkles
+	{0x274c, 0x274c},   // cross mark
+	{0x274e, 0x274e},   // cross mark button
+	{0x2753, 0x2755},   // question marks
+	{0x2757, 0x2757},   // exclamation mark
+	{0x2795, 0x2797},   // plus, minus, divide
+	{0x27b0, 0x27b0},   // curly loop
+	{0x27bf, 0x27bf},   // double curly loop
+	{0x2b1b, 0x2b1c},   // large squares
+	{0x2b50, 0x2b50},   // star
+	{0x2b55, 0x2b55},   // circle
+	{0x2e80, 0x303e},   // CJK radicals, symbols and punctuation
+	{0x3041, 0x33ff},   // kana, bopomofo, CJK compatibility
+	{0x3400, 0x4dbf},   // CJK extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo extended A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small forms
+	{0xff00, 0xff60},   // fullwidth forms
+	{0xffe0, 0xffe6},   // fullwidth signs
+	{0x16fe0, 0x16fe4}, // ideographic symbols
+	{0x17000, 0x18aff}, // Tangut
+	{0x1b000, 0x1b2ff}, // kana supplement and extensions, Nushu
+	{0x1f004, 0x1f004}, // mahjong tile
+	{0x1f0cf, 0x1f0cf}, // playing card
+	{0x1f18e, 0x1f18e}, // AB button
+	{0x1f191, 0x1f19a}, // squared words
+	{0x1f200, 0x1f251}, // enclosed ideographic supplement
+	{0x1f300, 0x1f64f}, // pictographs and emoticons
+	{0x1f680, 0x1f6ff}, // transport and map symbols
+	{0x1f7e0, 0x1f7eb}, // colored circles and squares
+	{0x1f90c, 0x1f9ff}, // supplemental symbols and pictographs
+	{0x1fa70, 0x1faff}, // symbols and pictographs extended A
+	{0x20000, 0x2fffd}, // CJK extensions B to F
+	{0x30000, 0x3fffd}, // CJK extension G
+}
+
+func isWide(r rune) bool {
+	lo, hi := 0, len(wide)
+	for lo < hi {
+		m := (lo + hi) / 2
+		switch {
+		case r < wide[m].lo:
+			hi = m
+		case r > wide[m].hi:
+			lo = m + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
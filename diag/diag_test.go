@@ -0,0 +1,38 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import "testing"
+
+func TestColumn(t *testing.T) {
+	tests := []struct {
+		line string
+		col  int
+		want int
+	}{
+		{"abc\n", 3, 3},
+		{"\tx", 2, 5},
+		{"ab\tx", 4, 5},
+		{"日本x", 3, 5},
+		{"e\u0301x", 3, 2}, // combining acute accent
+		{"ab\n", 5, 5},     // past the end of the line
+	}
+	for _, tt := range tests {
+		if got := Column(tt.line, tt.col); got != tt.want {
+			t.Errorf("Column(%q, %d) = %d, want %d", tt.line, tt.col, got, tt.want)
+		}
+	}
+	if got := ExpandTabs("a\tb\t"); got != "a   b   " {
+		t.Errorf("ExpandTabs = %q", got)
+	}
+}
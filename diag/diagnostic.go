@@ -0,0 +1,139 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Span is the source from Line and Col up to EndLine and EndCol, which is
+// not part of it. Columns count runes from 1. A zero EndLine is Line and a
+// zero EndCol marks the single rune at Col
+type Span struct {
+	Line, Col       int
+	EndLine, EndCol int
+}
+
+func (s Span) end() (line int, col int) {
+	line, col = s.EndLine, s.EndCol
+	if line == 0 {
+		line = s.Line
+	}
+	if col == 0 {
+		col = s.Col + 1
+	}
+	return line, col
+}
+
+// Label marks a span of the source. The primary label is where the error
+// is and is underlined with ^, the others with -
+type Label struct {
+	Span
+	Msg     string
+	Primary bool
+}
+
+// Note is a footer of a diagnostic, Kind is note or help
+type Note struct {
+	Kind string
+	Msg  string
+}
+
+// Diagnostic is an error along with the source it is about
+type Diagnostic struct {
+	// Severity heads the diagnostic, such as error
+	Severity string
+	Code     Code
+	Msg      string
+	File     string
+	Lines    []string
+	Labels   []Label
+	Notes    []Note
+	// Trace holds the calls that were running when a runtime error
+	// occurred, innermost first
+	Trace []Frame
+}
+
+// Frame is a call in the trace of a diagnostic, Source is the line it
+// stopped at
+type Frame struct {
+	Fn        string
+	File      string
+	Line, Col int
+	Source    string
+}
+
+// spanContext is the number of lines a multi line span can cover before the
+// lines between its first and last are left out
+const spanContext = 4
+
+// String draws d without colors
+func (d Diagnostic) String() string {
+	var str strings.Builder
+	Text{}.Render(&str, d)
+	return str.String()
+}
+
+// shown returns the sorted numbers of the lines the labels are on
+func (d Diagnostic) shown() []int {
+	seen := make(map[int]bool)
+	for _, l := range d.Labels {
+		endLine, _ := l.end()
+		seen[l.Line] = true
+		seen[endLine] = true
+		if endLine-l.Line < spanContext {
+			for n := l.Line + 1; n < endLine; n++ {
+				seen[n] = true
+			}
+		}
+	}
+	lines := make([]int, 0, len(seen))
+	for n := range seen {
+		lines = append(lines, n)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// line returns the text of line n, which is empty outside the source
+func (d Diagnostic) line(n int) string {
+	if n < 1 || n > len(d.Lines) {
+		return ""
+	}
+	return strings.TrimSuffix(d.Lines[n-1], "\n")
+}
+
+// on returns the display columns of the underline of l on line n, starting
+// from 0, and the message to put after it. A span over several lines is
+// underlined to the end of its first line and from the first rune that is
+// not a space on its last, where its message goes
+func (l Label) on(n int, line string) (start int, end int, msg string, ok bool) {
+	endLine, endCol := l.end()
+	switch {
+	case n == l.Line && n == endLine:
+		start, end, msg = Column(line, l.Col)-1, Column(line, endCol)-1, l.Msg
+	case n == l.Line:
+		start, end = Column(line, l.Col)-1, Column(line, len([]rune(line))+1)-1
+	case n == endLine:
+		first := 1 + len([]rune(line)) - len([]rune(strings.TrimLeftFunc(line, unicode.IsSpace)))
+		start, end, msg = Column(line, first)-1, Column(line, endCol)-1, l.Msg
+	default:
+		return 0, 0, "", false
+	}
+	if end <= start {
+		end = start + 1
+	}
+	return start, end, msg, true
+}
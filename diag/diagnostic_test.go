@@ -0,0 +1,74 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import "testing"
+
+func TestDiagnostic(t *testing.T) {
+	d := Diagnostic{
+		Severity: "error",
+		Msg:      "mismatched types",
+		File:     "test.gr",
+		Lines: []string{
+			"let s = \"日本\"\n",
+			"\ts = f(1,\n",
+			"\t\t2)\n",
+		},
+		Labels: []Label{
+			{Span: Span{Line: 2, Col: 6, EndLine: 3, EndCol: 5}, Msg: "int", Primary: true},
+			{Span: Span{Line: 1, Col: 9, EndCol: 13}, Msg: "string"},
+		},
+		Notes: []Note{{Kind: "help", Msg: "convert it with string(...)"}},
+	}
+	want := `error: mismatched types
+ --> test.gr:2:6
+  |
+1 | let s = "日本"
+  |         ------ string
+2 |     s = f(1,
+  |         ^^^^
+3 |         2)
+  |         ^^ int
+  = help: convert it with string(...)
+`
+	if got := d.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	f := Frame{Fn: "f", File: "test.gr", Line: 1, Col: 19, Source: "fn f(n: int): int f(n + 1) end\n"}
+	d := Diagnostic{
+		Severity: "runtime error",
+		Msg:      "stack overflow",
+		File:     "test.gr",
+		Lines:    []string{f.Source},
+		Labels:   []Label{{Span: Span{Line: 1, Col: 19}, Msg: "stack overflow", Primary: true}},
+		Trace:    []Frame{f, f, f, {Fn: "<top level>", File: "test.gr", Line: 2, Col: 1}},
+	}
+	want := `runtime error: stack overflow
+ --> test.gr:1:19
+  |
+1 | fn f(n: int): int f(n + 1) end
+  |                   ^ stack overflow
+
+stack trace:
+  at f (test.gr:1:19)
+      fn f(n: int): int f(n + 1) end
+  ... repeated 2 more times
+  at <top level> (test.gr:2:1)
+`
+	if got := d.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"zimlit/graphene/checker"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/parser"
+)
+
+func TestCodes(t *testing.T) {
+	codes := diag.Codes()
+	for i, code := range codes {
+		if want := diag.Code(fmt.Sprintf("E%04d", i+1)); code != want {
+			t.Fatalf("code %d is %s, want %s", i, code, want)
+		}
+		if title := diag.Title(code); !strings.HasPrefix(title, string(code)+": ") {
+			t.Errorf("%s has title %q", code, title)
+		}
+	}
+	if last := codes[len(codes)-1]; last != diag.ImportCycle {
+		t.Errorf("last code is %s, want %s", last, diag.ImportCycle)
+	}
+	if _, err := diag.Explain("E9999"); err == nil {
+		t.Error("explained an unknown code")
+	}
+}
+
+// check parses and checks src and returns the codes of its diagnostics
+func check(src string) []diag.Code {
+	f, err := parser.ParseSource(context.Background(), "example.gr", src)
+	if err == nil {
+		c := checker.NewChecker(f.Lines, f.Name)
+		_, err = c.Check(f.Stmts)
+	}
+	if err == nil {
+		return nil
+	}
+	var derr diag.Error
+	if !errors.As(err, &derr) {
+		return []diag.Code{"not a diagnostic"}
+	}
+	var codes []diag.Code
+	for _, d := range derr.Diagnostics() {
+		codes = append(codes, d.Code)
+	}
+	return codes
+}
+
+// examples returns the graphene code blocks of text
+func examples(text string) []string {
+	var blocks []string
+	for {
+		_, rest, ok := strings.Cut(text, "```graphene\n")
+		if !ok {
+			return blocks
+		}
+		block, rest, _ := strings.Cut(rest, "```")
+		blocks = append(blocks, block)
+		text = rest
+	}
+}
+
+func TestExplainExamples(t *testing.T) {
+	for _, code := range diag.Codes() {
+		text, err := diag.Explain(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// examples that import need other files and are left to the
+		// loader
+		if strings.Contains(text, "import") {
+			continue
+		}
+		blocks := examples(text)
+		if len(blocks) != 2 {
+			t.Errorf("%s has %d examples, want an erroneous and a corrected one", code, len(blocks))
+			continue
+		}
+		if got := check(blocks[0]); len(got) == 0 || got[0] != code {
+			t.Errorf("erroneous example of %s reports %v", code, got)
+		}
+		if got := check(blocks[1]); len(got) != 0 {
+			t.Errorf("corrected example of %s reports %v", code, got)
+		}
+	}
+}
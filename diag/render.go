@@ -0,0 +1,216 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// Renderer draws diagnostics
+type Renderer interface {
+	Render(w io.Writer, d Diagnostic) error
+}
+
+// Error is an error made of diagnostics
+type Error interface {
+	error
+	Diagnostics() []Diagnostic
+}
+
+// Fprint writes err to w, the diagnostics of an Error are drawn with r
+func Fprint(w io.Writer, r Renderer, err error) error {
+	var e Error
+	if !errors.As(err, &e) {
+		_, err := fmt.Fprintln(w, err)
+		return err
+	}
+	for _, d := range e.Diagnostics() {
+		if err := r.Render(w, d); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Color modes of UseColor
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// UseColor reports whether diagnostics written to f are colored in mode.
+// In auto mode a set NO_COLOR turns colors off and a CLICOLOR_FORCE other
+// than 0 turns them on, otherwise f is colored when it is a terminal
+func UseColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto:
+	default:
+		return false, fmt.Errorf("unknown color mode %q, expected auto, always or never", mode)
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false, nil
+	}
+	if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true, nil
+	}
+	return f != nil && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())), nil
+}
+
+// Text draws diagnostics the way a terminal shows them, with escape codes
+// for colors when Color is set
+type Text struct {
+	Color bool
+}
+
+type printer func(w io.Writer, format string, a ...any)
+
+// printer returns a printer of text in the color of attrs. The Fprint
+// functions of color leave out the reset when stdout is not a terminal, the
+// text is formatted with Sprint instead, which only looks at t.Color
+func (t Text) printer(attrs ...color.Attribute) printer {
+	c := color.New(attrs...)
+	if t.Color {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	sprintf := c.SprintfFunc()
+	return func(w io.Writer, format string, a ...any) {
+		io.WriteString(w, sprintf(format, a...))
+	}
+}
+
+func (t Text) Render(w io.Writer, d Diagnostic) error {
+	var str strings.Builder
+	red := t.printer(color.FgHiRed, color.Bold)
+	white := t.printer(color.FgHiWhite, color.Bold)
+	b := t.printer(color.FgHiBlue, color.Bold)
+
+	if d.Code != "" {
+		red(&str, "%s[%s]", d.Severity, d.Code)
+	} else {
+		red(&str, "%s", d.Severity)
+	}
+	fmt.Fprint(&str, ": ")
+	white(&str, "%s\n", d.Msg)
+	gutter := ""
+	if len(d.Labels) > 0 {
+		gutter = t.source(&str, d)
+	}
+	for _, note := range d.Notes {
+		b(&str, "%s = ", gutter)
+		white(&str, "%s", note.Kind)
+		fmt.Fprintf(&str, ": %s\n", note.Msg)
+	}
+	if len(d.Trace) > 0 {
+		fmt.Fprintln(&str)
+		white(&str, "stack trace:\n")
+		for i := 0; i < len(d.Trace); i++ {
+			f := d.Trace[i]
+			fmt.Fprintf(&str, "  at %s (%s:%d:%d)\n", f.Fn, f.File, f.Line, f.Col)
+			if line := strings.TrimSpace(f.Source); line != "" {
+				fmt.Fprintf(&str, "      %s\n", line)
+			}
+			// deep recursion repeats a frame, it is shown once
+			repeats := 0
+			for i+1 < len(d.Trace) && d.Trace[i+1] == f {
+				repeats++
+				i++
+			}
+			if repeats > 0 {
+				fmt.Fprintf(&str, "  ... repeated %d more times\n", repeats)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, str.String())
+	return err
+}
+
+// source draws the lines of d that its labels are on and returns the gutter
+// of the line numbers
+func (t Text) source(str *strings.Builder, d Diagnostic) string {
+	red := t.printer(color.FgHiRed, color.Bold)
+	b := t.printer(color.FgHiBlue, color.Bold)
+
+	primary := d.Labels[0]
+	for _, l := range d.Labels {
+		if l.Primary {
+			primary = l
+			break
+		}
+	}
+	lines := d.shown()
+	width := len(strconv.Itoa(lines[len(lines)-1]))
+	gutter := strings.Repeat(" ", width)
+	b(str, "%s--> ", gutter)
+	fmt.Fprintf(str, "%s:%d:%d\n", d.File, primary.Line, primary.Col)
+	b(str, "%s |\n", gutter)
+	for i, n := range lines {
+		if i > 0 && n > lines[i-1]+1 {
+			b(str, "...\n")
+		}
+		line := d.line(n)
+		b(str, "%*d | ", width, n)
+		fmt.Fprintln(str, ExpandTabs(line))
+		for _, l := range d.Labels {
+			start, end, msg, ok := l.on(n, line)
+			if !ok {
+				continue
+			}
+			mark, c := "-", b
+			if l.Primary {
+				mark, c = "^", red
+			}
+			b(str, "%s | ", gutter)
+			fmt.Fprint(str, strings.Repeat(" ", start))
+			if msg == "" {
+				c(str, "%s\n", strings.Repeat(mark, end-start))
+			} else {
+				c(str, "%s %s\n", strings.Repeat(mark, end-start), msg)
+			}
+		}
+	}
+	return gutter
+}
+
+// Collect returns the diagnostics of errs, an error that is not an Error
+// becomes a diagnostic holding only its message
+func Collect(errs []error) []Diagnostic {
+	var ds []Diagnostic
+	for _, err := range errs {
+		var e Error
+		if errors.As(err, &e) {
+			ds = append(ds, e.Diagnostics()...)
+		} else {
+			ds = append(ds, Diagnostic{Severity: "error", Msg: err.Error()})
+		}
+	}
+	return ds
+}
@@ -0,0 +1,81 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package diag
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		mode, noColor, force string
+		want                 bool
+	}{
+		{ColorAlways, "1", "", true},
+		{ColorNever, "", "1", false},
+		{ColorAuto, "", "", false},
+		{ColorAuto, "1", "", false},
+		{ColorAuto, "", "1", true},
+		{ColorAuto, "", "0", false},
+		{ColorAuto, "1", "1", false},
+	}
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, tt := range tests {
+		t.Setenv("NO_COLOR", tt.noColor)
+		t.Setenv("CLICOLOR_FORCE", tt.force)
+		got, err := UseColor(tt.mode, f)
+		if err != nil || got != tt.want {
+			t.Errorf("UseColor(%q) with NO_COLOR=%q CLICOLOR_FORCE=%q = %v, %v, want %v", tt.mode, tt.noColor, tt.force, got, err, tt.want)
+		}
+	}
+	if _, err := UseColor("sometimes", f); err == nil {
+		t.Errorf("UseColor accepted an unknown mode")
+	}
+}
+
+type diagErr []Diagnostic
+
+func (d diagErr) Error() string             { return "diagnostics" }
+func (d diagErr) Diagnostics() []Diagnostic { return d }
+
+func TestFprint(t *testing.T) {
+	d := Diagnostic{Severity: "error", Msg: "bad", File: "t.gr", Lines: []string{"x\n"},
+		Labels: []Label{{Span: Span{Line: 1, Col: 1}, Msg: "bad", Primary: true}}}
+
+	var plain, colored strings.Builder
+	Fprint(&plain, Text{}, diagErr{d})
+	Fprint(&colored, Text{Color: true}, diagErr{d})
+	if plain.String() != d.String()+"\n" {
+		t.Errorf("got\n%s\nwant\n%s", plain.String(), d.String())
+	}
+	if strings.Contains(plain.String(), "\x1b[") {
+		t.Errorf("plain output has escape codes: %q", plain.String())
+	}
+	// every color is reset
+	if n := strings.Count(colored.String(), "\x1b[0m"); n == 0 || n != strings.Count(colored.String(), "\x1b[")-n {
+		t.Errorf("unbalanced escape codes: %q", colored.String())
+	}
+
+	var other strings.Builder
+	Fprint(&other, Text{Color: true}, errors.New("boom"))
+	if other.String() != "boom\n" {
+		t.Errorf("got %q, want %q", other.String(), "boom\n")
+	}
+}
@@ -3,13 +3,15 @@ module zimlit/graphene
 go 1.18
 
 require (
-	github.com/chzyer/readline v1.5.0 // indirect
-	github.com/fatih/color v1.13.0 // indirect
+	github.com/chzyer/readline v1.5.0
+	github.com/fatih/color v1.13.0
+	github.com/mattn/go-isatty v0.0.14
+	github.com/spf13/cobra v1.5.0
+)
+
+require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/mattn/go-colorable v0.1.9 // indirect
-	github.com/mattn/go-isatty v0.0.14 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	github.com/spf13/cobra v1.5.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.0.0-20220310020820-b874c991c1a5 // indirect
 )
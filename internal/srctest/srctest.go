@@ -0,0 +1,84 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package srctest generates and checks sources for the tests of the lexer
+// and the parser
+package srctest
+
+import (
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+)
+
+// Edit replaces the source from StartLine:StartCol up to EndLine:EndCol with
+// Text, it converts to lexer.Edit
+type Edit struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	Text      string
+}
+
+// snippets are the replacement texts of the random edits
+var snippets = []string{
+	"", "\n", "x", "1", " + 2", "\"s\"", "\"", "(", ")", "end", "end\n",
+	"if x\n", "else", "else if y", "fn f(a: int): int\n", "let y = 3\n",
+	";", "while x\n", "return 1\n", "g(1, 2)", "$", "é", "\t", "\n\n",
+}
+
+// position returns the line and column of the rune at offset in src
+func position(src []rune, offset int) (line int, col int) {
+	line, col = 1, 1
+	for _, c := range src[:offset] {
+		col++
+		if c == '\n' {
+			line, col = line+1, 1
+		}
+	}
+	return line, col
+}
+
+// RandomEdit returns an edit of src and the source it produces, half of the
+// edits replace at most 4 runes
+func RandomEdit(r *rand.Rand, src string) (Edit, string) {
+	runes := []rune(src)
+	start := r.Intn(len(runes) + 1)
+	end := start + r.Intn(len(runes)-start+1)
+	if r.Intn(2) == 0 {
+		end = start + r.Intn(min(len(runes)-start, 4)+1)
+	}
+	text := snippets[r.Intn(len(snippets))]
+
+	var e Edit
+	e.StartLine, e.StartCol = position(runes, start)
+	e.EndLine, e.EndCol = position(runes, end)
+	e.Text = text
+	return e, string(runes[:start]) + text + string(runes[end:])
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// InSource reports whether line:col is a character of lines or the end of
+// one of them
+func InSource(lines []string, line int, col int) bool {
+	if line < 1 || line > len(lines) {
+		return line == 1 && col == 1 && len(lines) == 0
+	}
+	return col >= 1 && col <= utf8.RuneCountInString(strings.TrimSuffix(lines[line-1], "\n"))+1
+}
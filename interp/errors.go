@@ -0,0 +1,104 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package interp
+
+import (
+	"fmt"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
+)
+
+// Frame is a fn that was running when a runtime error occurred
+type Frame struct {
+	Fn      string
+	File    string
+	Pos     ast.Pos
+	LineStr string
+}
+
+type RuntimeErr struct {
+	msg string
+	pos ast.Pos
+	// at is the position reached in the frame being unwound
+	at    ast.Pos
+	trace []Frame
+	// lines is the source the error occurred in
+	lines []string
+}
+
+// Trace returns the frames of the error, innermost first
+func (r RuntimeErr) Trace() []Frame {
+	return r.trace
+}
+
+func (r RuntimeErr) Error() string {
+	if len(r.trace) == 0 {
+		return fmt.Sprintf("%d:%d: runtime error: %s", r.pos.Line, r.pos.Col, r.msg)
+	}
+	return r.diagnostic().String()
+}
+
+func (r RuntimeErr) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{r.diagnostic()}
+}
+
+func (r RuntimeErr) diagnostic() diag.Diagnostic {
+	d := diag.Diagnostic{Severity: "runtime error", Msg: r.msg}
+	if len(r.trace) == 0 {
+		return d
+	}
+	d.File, d.Lines = r.trace[0].File, r.lines
+	d.Labels = []diag.Label{{
+		Span:    diag.Span{Line: r.pos.Line, Col: r.pos.Col},
+		Msg:     r.msg,
+		Primary: true,
+	}}
+	// an error at the top level has no calls to show
+	if len(r.trace) == 1 {
+		return d
+	}
+	for _, f := range r.trace {
+		d.Trace = append(d.Trace, diag.Frame{
+			Fn:     f.Fn,
+			File:   f.File,
+			Line:   f.Pos.Line,
+			Col:    f.Pos.Col,
+			Source: f.LineStr,
+		})
+	}
+	return d
+}
+
+func newRuntimeErr(pos ast.Pos, format string, args ...any) RuntimeErr {
+	return RuntimeErr{
+		msg: fmt.Sprintf(format, args...),
+		pos: pos,
+		at:  pos,
+	}
+}
+
+// unwind records the frame of fn, running in src, that the error is
+// propagating out of. call is where that frame was called from
+func (r RuntimeErr) unwind(fn string, src *source, call ast.Pos) RuntimeErr {
+	if r.trace == nil {
+		r.lines = src.lines
+	}
+	r.trace = append(r.trace, Frame{
+		Fn:      fn,
+		File:    src.fname,
+		Pos:     r.at,
+		LineStr: src.lineStr(r.at.Line),
+	})
+	r.at = call
+	return r
+}
@@ -0,0 +1,448 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package interp
+
+import (
+	"os"
+	"strconv"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/token"
+)
+
+type Interpreter struct {
+	host    *builtins.Host
+	src     *source
+	globals *Environment
+	env     *Environment
+	modules map[string]*Module
+	pub     []string
+	// depth is the number of calls running
+	depth int
+}
+
+// maxDepth is the number of nested calls past which a program is stopped
+// with a stack overflow, before it exhausts the stack of the Go runtime
+const maxDepth = 10000
+
+func NewInterpreter(lines []string, fname string) Interpreter {
+	globals := newEnvironment(nil)
+	for _, b := range builtins.All() {
+		globals.define(b.Name, b)
+	}
+	return Interpreter{
+		host:    &builtins.Host{Stdout: os.Stdout},
+		src:     &source{lines, fname},
+		globals: globals,
+		env:     globals,
+		modules: make(map[string]*Module),
+	}
+}
+
+// SetHost sets the host builtins run in, interpreters of the modules of one
+// program share a host
+func (i *Interpreter) SetHost(h *builtins.Host) {
+	i.host = h
+}
+
+// SetSource sets the source runtime errors are reported against, the inputs
+// of an interactive session each have their own
+func (i *Interpreter) SetSource(lines []string, fname string) {
+	i.src = &source{lines, fname}
+}
+
+// BeginInput starts an input of an interactive session. The input gets its
+// own copy of the global bindings, sharing their cells, so a global it
+// redeclares shadows the global the fns of earlier inputs keep using
+func (i *Interpreter) BeginInput() {
+	globals := newEnvironment(nil)
+	for name, c := range i.globals.values {
+		globals.values[name] = c
+	}
+	i.globals, i.env = globals, globals
+}
+
+// Globals is a copy of the global bindings of an interpreter
+type Globals struct {
+	values map[string]*cell
+}
+
+func (i *Interpreter) SaveGlobals() Globals {
+	g := Globals{make(map[string]*cell)}
+	for name, c := range i.globals.values {
+		g.values[name] = c
+	}
+	return g
+}
+
+// RestoreGlobals replaces the global bindings with g, undoing the
+// declarations made since it was saved. Assignments to existing bindings
+// are kept
+func (i *Interpreter) RestoreGlobals(g Globals) {
+	i.globals.values = make(map[string]*cell)
+	for name, c := range g.values {
+		i.globals.values[name] = c
+	}
+}
+
+// AddModule makes m available to import declarations
+func (i *Interpreter) AddModule(m *Module) {
+	i.modules[m.Path] = m
+}
+
+// Module returns the pub bindings of the interpreted file
+func (i *Interpreter) Module(path string) *Module {
+	m := &Module{
+		Path:    path,
+		exports: make(map[string]*cell),
+	}
+	for _, name := range i.pub {
+		m.exports[name] = i.globals.values[name]
+	}
+
+	return m
+}
+
+type returnSignal struct {
+	value any
+}
+
+// Interpret executes stmts, which must have been checked by the checker,
+// and returns the value of the last one. A call to exit stops the program
+// with a builtins.Exit error
+func (i *Interpreter) Interpret(stmts ast.Stmts) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case RuntimeErr:
+				err = r.unwind("<top level>", i.src, ast.Pos{})
+			case builtins.Exit:
+				err = r
+			default:
+				panic(r)
+			}
+			i.env = i.globals
+		}
+	}()
+
+	for _, stmt := range stmts {
+		value = i.exec(stmt)
+	}
+	return value, nil
+}
+
+func (i *Interpreter) eval(expr ast.Expr) any {
+	return expr.Accept(i)
+}
+
+func (i *Interpreter) block(body []ast.Stmt) any {
+	prev := i.env
+	i.env = newEnvironment(prev)
+	defer func() { i.env = prev }()
+
+	var value any
+	for _, stmt := range body {
+		value = i.exec(stmt)
+	}
+	return value
+}
+
+func (i *Interpreter) truthy(value any, pos ast.Pos) bool {
+	v, ok := value.(int64)
+	if !ok {
+		panic(newRuntimeErr(pos, "nil used as condition"))
+	}
+	return v != 0
+}
+
+func (i *Interpreter) call(fn *Closure, args []any, pos ast.Pos) (value any) {
+	if i.depth == maxDepth {
+		panic(newRuntimeErr(pos, "stack overflow"))
+	}
+	env := newEnvironment(fn.globals)
+	for name, c := range fn.upvalues {
+		env.values[name] = c
+	}
+	for j, p := range fn.Fn.Params {
+		env.define(p.Name, args[j])
+	}
+
+	prev, prevSrc := i.env, i.src
+	i.env, i.src = env, fn.src
+	i.depth++
+	defer func() {
+		i.env, i.src = prev, prevSrc
+		i.depth--
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case returnSignal:
+				value = r.value
+			case RuntimeErr:
+				panic(r.unwind(fn.Name(), fn.src, pos))
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	for _, stmt := range fn.Fn.Body {
+		value = i.exec(stmt)
+	}
+	return value
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) VisitBinary(b ast.Binary) any {
+	left := i.eval(b.Left)
+	right := i.eval(b.Right)
+
+	switch b.Operator.Kind {
+	case token.EQEQ:
+		return boolToInt(left == right)
+	case token.NEQ:
+		return boolToInt(left != right)
+	}
+
+	if left == nil || right == nil {
+		panic(newRuntimeErr(b.Position(), "nil operand to %s", b.Operator.Kind))
+	}
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		switch b.Operator.Kind {
+		case token.PLUS:
+			return l + r
+		case token.MINUS:
+			return l - r
+		case token.STAR:
+			return l * r
+		case token.SLASH:
+			if r == 0 {
+				panic(newRuntimeErr(b.Position(), "integer division by zero"))
+			}
+			return l / r
+		case token.PERCENT:
+			if r == 0 {
+				panic(newRuntimeErr(b.Position(), "integer division by zero"))
+			}
+			return l % r
+		case token.AMP:
+			return l & r
+		case token.PIPE:
+			return l | r
+		case token.CARET:
+			return l ^ r
+		case token.SHL, token.SHR:
+			if r < 0 {
+				panic(newRuntimeErr(b.Position(), "negative shift count %d", r))
+			}
+			if b.Operator.Kind == token.SHL {
+				return l << r
+			}
+			return l >> r
+		case token.LESS:
+			return boolToInt(l < r)
+		case token.LESSEQ:
+			return boolToInt(l <= r)
+		case token.GREATER:
+			return boolToInt(l > r)
+		case token.GREATEREQ:
+			return boolToInt(l >= r)
+		}
+	case float64:
+		r := right.(float64)
+		switch b.Operator.Kind {
+		case token.PLUS:
+			return l + r
+		case token.MINUS:
+			return l - r
+		case token.STAR:
+			return l * r
+		case token.SLASH:
+			return l / r
+		case token.LESS:
+			return boolToInt(l < r)
+		case token.LESSEQ:
+			return boolToInt(l <= r)
+		case token.GREATER:
+			return boolToInt(l > r)
+		case token.GREATEREQ:
+			return boolToInt(l >= r)
+		}
+	case string:
+		r := right.(string)
+		switch b.Operator.Kind {
+		case token.PLUS:
+			return l + r
+		case token.LESS:
+			return boolToInt(l < r)
+		case token.LESSEQ:
+			return boolToInt(l <= r)
+		case token.GREATER:
+			return boolToInt(l > r)
+		case token.GREATEREQ:
+			return boolToInt(l >= r)
+		}
+	}
+
+	panic(newRuntimeErr(b.Position(), "invalid operation %s", b.Operator.Kind))
+}
+
+func (i *Interpreter) VisitUnary(u ast.Unary) any {
+	right := i.eval(u.Right)
+	if right == nil {
+		panic(newRuntimeErr(u.Position(), "nil operand to %s", u.Operator.Kind))
+	}
+
+	switch u.Operator.Kind {
+	case token.MINUS:
+		switch r := right.(type) {
+		case int64:
+			return -r
+		case float64:
+			return -r
+		}
+	case token.BANG:
+		return boolToInt(right.(int64) == 0)
+	case token.TILDE:
+		return ^right.(int64)
+	}
+
+	panic(newRuntimeErr(u.Position(), "invalid operation %s", u.Operator.Kind))
+}
+
+func (i *Interpreter) VisitLiteral(l ast.Literal) any {
+	switch l.Kind {
+	case token.INT:
+		v, err := strconv.ParseInt(l.Value, 10, 64)
+		if err != nil {
+			panic(newRuntimeErr(l.Pos, "invalid int literal %s", l.Value))
+		}
+		return v
+	case token.FLOAT:
+		v, err := strconv.ParseFloat(l.Value, 64)
+		if err != nil {
+			panic(newRuntimeErr(l.Pos, "invalid float literal %s", l.Value))
+		}
+		return v
+	case token.STRING:
+		return l.Value[1 : len(l.Value)-1]
+	case token.NIL:
+		return nil
+	}
+
+	c := i.env.lookup(l.Value)
+	if c == nil {
+		panic(newRuntimeErr(l.Pos, "undefined: %s", l.Value))
+	}
+	return c.value
+}
+
+func (i *Interpreter) VisitGrouping(g ast.Grouping) any {
+	return i.eval(g.Inner)
+}
+
+func (i *Interpreter) VisitIfExpr(e ast.IfExpr) any {
+	if i.truthy(i.eval(e.Condition), e.Condition.Position()) {
+		return i.block(e.Body)
+	}
+	for _, elif := range e.Else_ifs {
+		if i.truthy(i.eval(elif.Condition), elif.Condition.Position()) {
+			return i.block(elif.Body)
+		}
+	}
+	if e.Else != nil {
+		return i.block(e.Else)
+	}
+
+	return nil
+}
+
+func (i *Interpreter) VisitAssignment(a ast.Assignment) any {
+	value := i.eval(a.Value)
+	c := i.env.lookup(a.Name)
+	if c == nil {
+		panic(newRuntimeErr(a.Pos, "undefined: %s", a.Name))
+	}
+	c.value = value
+	return value
+}
+
+func (i *Interpreter) VisitFnExpr(f ast.FnExpr) any {
+	upvalues := make(map[string]*cell)
+	for _, u := range f.Captures {
+		c := i.env.lookup(u.Name)
+		if c == nil {
+			panic(newRuntimeErr(f.Pos, "undefined: %s", u.Name))
+		}
+		if !u.ByRef {
+			c = &cell{c.value}
+		}
+		upvalues[u.Name] = c
+	}
+
+	return &Closure{
+		Fn:       f,
+		upvalues: upvalues,
+		globals:  i.globals,
+		src:      i.src,
+	}
+}
+
+func (i *Interpreter) VisitCallExpr(c ast.Call) any {
+	callee := i.eval(c.Callee)
+	args := []any{}
+	for _, arg := range c.Arguments {
+		args = append(args, i.eval(arg))
+	}
+
+	switch fn := callee.(type) {
+	case *Closure:
+		return i.call(fn, args, c.Position())
+	case *builtins.Builtin:
+		value, err := fn.Call(i.host, args)
+		if exit, ok := err.(builtins.Exit); ok {
+			panic(exit)
+		}
+		if err != nil {
+			panic(newRuntimeErr(c.Position(), "%s", err))
+		}
+		return value
+	}
+	panic(newRuntimeErr(c.Position(), "call of nil fn"))
+}
+
+func (i *Interpreter) VisitGet(g ast.Get) any {
+	m, ok := i.eval(g.Object).(*Module)
+	if !ok {
+		panic(newRuntimeErr(g.Pos, "selector on value that is not a module"))
+	}
+	c, ok := m.exports[g.Name]
+	if !ok {
+		panic(newRuntimeErr(g.Pos, "%s is not exported by module %s", g.Name, m.Path))
+	}
+	return c.value
+}
+
+// generic fns are not specialised, values carry their own type at runtime
+// so the same body works for every instantiation
+func (i *Interpreter) VisitInstantiate(in ast.Instantiate) any {
+	return i.eval(in.Fn)
+}
@@ -0,0 +1,209 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package interp_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/checker"
+	"zimlit/graphene/interp"
+	"zimlit/graphene/parser"
+)
+
+// run checks and interprets src, returning what it printed
+func run(t *testing.T, src string) (string, error) {
+	t.Helper()
+	f, err := parser.ParseSource(context.Background(), "test.gr", src)
+	if err != nil {
+		t.Fatalf("parsing %q: %s", src, err)
+	}
+	c := checker.NewChecker(f.Lines, f.Name)
+	stmts, err := c.Check(f.Stmts)
+	if err != nil {
+		t.Fatalf("checking %q: %s", src, err)
+	}
+	var out strings.Builder
+	in := interp.NewInterpreter(f.Lines, f.Name)
+	in.SetHost(&builtins.Host{Stdout: &out})
+	_, err = in.Interpret(stmts)
+	return out.String(), err
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"adder", "fn adder(n: int): fn(int): int\n\tfn (x: int): int x + n end\nend\nlet add2 = adder(2)\nprintln(add2(3))\nprintln(adder(10)(3))", "5\n13\n"},
+		{"mut by reference", "fn counter(): fn(): int\n\tlet mut n = 0\n\tfn (): int\n\t\tn = n + 1\n\t\tn\n\tend\nend\nlet c = counter()\nlet d = counter()\nc()\nprintln(c())\nprintln(d())", "2\n1\n"},
+		{"shared cell", "fn pair(): int\n\tlet mut n = 0\n\tlet inc = fn (): int\n\t\tn = n + 1\n\t\tn\n\tend\n\tinc()\n\tinc()\n\tn\nend\nprintln(pair())", "2\n"},
+		{"value", "fn f(): fn(): int\n\tlet x = 1\n\tfn (): int x end\nend\nprintln(f()())", "1\n"},
+		{"recursive", "fn outer(): int\n\tfn fact(n: int): int if n > 1 n * fact(n - 1) else 1 end end\n\tfact(5)\nend\nprintln(outer())", "120\n"},
+		{"recursive let", "let f = fn (n: int): int if n > 1 n * f(n - 1) else 1 end end\nprintln(f(4))", "24\n"},
+		{"higher order", "fn twice(f: fn(int): int, x: int): int f(f(x)) end\nprintln(twice(fn (x: int): int x * 3 end, 2))", "18\n"},
+		{"top level if", "let mut fs = fn (): int 0 end\nif 1\n\tlet k = 42\n\tfs = fn (): int k end\nend\nprintln(fs())", "42\n"},
+		{"top level while", "let mut i = 0\nlet mut g = fn (): int 0 end\nwhile i < 3\n\tlet j = i\n\tg = fn (): int j end\n\ti = i + 1\nend\nprintln(g())", "2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	_, err := run(t, "fn f(n: int): int f(n + 1) end\nf(0)")
+	rerr, ok := err.(interp.RuntimeErr)
+	if !ok {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	if !strings.Contains(rerr.Error(), "stack overflow") {
+		t.Errorf("got %s, want a stack overflow", rerr)
+	}
+	if trace := rerr.Trace(); len(trace) < 2 || trace[0].Fn != "f" || trace[len(trace)-1].Fn != "<top level>" {
+		t.Errorf("got trace of %d frames", len(trace))
+	}
+	if got, err := run(t, "fn sum(n: int): int if n > 0 n + sum(n - 1) else 0 end end\nprintln(sum(1000))"); err != nil || got != "500500\n" {
+		t.Errorf("deep recursion printed %q, %v", got, err)
+	}
+}
+
+func TestBuiltinNilArgument(t *testing.T) {
+	tests := []string{
+		"println(sqrt(nil))",
+		"let s: string = nil\nprintln(len(s))",
+		"println(pow(2.0, nil))",
+		"println(substr(\"abc\", nil, 1))",
+		"exit(nil)",
+	}
+	for _, src := range tests {
+		_, err := run(t, src)
+		if _, ok := err.(interp.RuntimeErr); !ok || !strings.Contains(err.Error(), "nil passed as argument") {
+			t.Errorf("%q: got %v, want a runtime error about the nil argument", src, err)
+		}
+	}
+	if got, err := run(t, "let s: string = nil\nprintln(s)\nprintln(string(s))"); err != nil || got != "nil\nnil\n" {
+		t.Errorf("printing nil printed %q, %v", got, err)
+	}
+}
+
+func TestMutualRecursion(t *testing.T) {
+	got, err := run(t, "fn even(n: int): int if n == 0 1 else odd(n - 1) end end\nfn odd(n: int): int if n == 0 0 else even(n - 1) end end\nprintln(even(10))\nprintln(odd(7))")
+	if err != nil || got != "1\n1\n" {
+		t.Errorf("printed %q, %v", got, err)
+	}
+}
+
+// TestInputShadows runs inputs the way an interactive session does, a
+// global redeclared by a later input is not seen by the fns of earlier ones
+func TestInputShadows(t *testing.T) {
+	c := checker.NewChecker(nil, "<stdin>")
+	in := interp.NewInterpreter(nil, "<stdin>")
+	var out strings.Builder
+	in.SetHost(&builtins.Host{Stdout: &out})
+	for _, src := range []string{
+		"let x = 1\nlet mut n = 0\nfn f(): int x end\nfn inc(): int n = n + 1 end",
+		"let x = \"a\"\nlet n = \"s\"",
+		"inc()\nprintln(f() + inc())\nprintln(x + n)",
+	} {
+		f, err := parser.ParseSource(context.Background(), "<stdin>", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmts, _, err := c.CheckInput(f.Stmts, f.Lines, f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in.BeginInput()
+		in.SetSource(f.Lines, f.Name)
+		if _, err := in.Interpret(stmts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := out.String(); got != "3\nas\n" {
+		t.Errorf("printed %q, want %q", got, "3\nas\n")
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	decls := "fn max[T: numeric](a: T, b: T): T if a > b a else b end end\nfn eq[T: comparable](a: T, b: T): int a == b end\nfn id[T](x: T): T x end\n"
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"inferred int", "println(max(1, 2))", "2\n"},
+		{"inferred float", "println(max(1.5, 0.5))", "1.5\n"},
+		{"inferred string", "println(eq(\"a\", \"a\"))\nprintln(id(\"s\"))", "1\ns\n"},
+		{"explicit", "println(max[float](1.0, 2.5))", "2.5\n"},
+		{"explicit value", "let m = max[int]\nprintln(m(3, 4))", "4\n"},
+		{"explicit nil", "println(id[string](nil))", "nil\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, decls+tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntOperators(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{"modulo", "println(7 % 3)\nprintln(-7 % 3)\nprintln(7 % -3)", "1\n-1\n1\n", ""},
+		{"bitwise", "println(12 & 10)\nprintln(12 | 10)\nprintln(12 ^ 10)\nprintln(~0)", "8\n14\n6\n-1\n", ""},
+		{"shifts", "println(1 << 4)\nprintln(-16 >> 2)\nprintln(1 << 64)\nprintln(3 << 0)", "16\n-4\n0\n3\n", ""},
+		{"compound", "let mut x = 10\nx += 1\nx -= 2\nx *= 1 + 2\nx /= 4\nprintln(x)\nx %= 3 + 1\nprintln(x)", "6\n2\n", ""},
+		{"compound string", "let mut s = \"a\"\ns += \"b\"\nprintln(s)", "ab\n", ""},
+		{"division by zero", "let z = 0\nprintln(1 / z)", "", "integer division by zero"},
+		{"modulo by zero", "let z = 0\nprintln(1 % z)", "", "integer division by zero"},
+		{"compound modulo by zero", "let mut x = 1\nx %= 0", "", "integer division by zero"},
+		{"negative shift", "let n = -1\nprintln(1 << n)", "", "negative shift count -1"},
+		{"negative right shift", "println(8 >> (2 - 3))", "", "negative shift count -1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.src)
+			if tt.err != "" {
+				if _, ok := err.(interp.RuntimeErr); !ok || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want a runtime error %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
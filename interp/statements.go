@@ -0,0 +1,58 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package interp
+
+import "zimlit/graphene/ast"
+
+// exec runs stmt, an expression statement evaluates to the value of its
+// expression and every other statement to nil
+func (i *Interpreter) exec(stmt ast.Stmt) any {
+	return stmt.Accept(i)
+}
+
+func (i *Interpreter) VisitExprStmt(e ast.ExprStmt) any {
+	return i.eval(e.Expr)
+}
+
+func (i *Interpreter) VisitVarDecl(v ast.VarDecl) any {
+	// the binding exists before its value so fns can refer to themselves
+	c := i.env.define(v.Name, nil)
+	c.value = i.eval(v.Value)
+	if _, ok := v.Value.(ast.FnExpr); ok {
+		c.value.(*Closure).name = v.Name
+	}
+	if v.IsPub() {
+		i.pub = append(i.pub, v.Name)
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitWhileStmt(w ast.WhileStmt) any {
+	for i.truthy(i.eval(w.Cond), w.Cond.Position()) {
+		i.block(w.Body)
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitReturnStmt(r ast.Return) any {
+	panic(returnSignal{i.eval(r.Value)})
+}
+
+func (i *Interpreter) VisitImport(im ast.Import) any {
+	m, ok := i.modules[im.Path]
+	if !ok {
+		panic(newRuntimeErr(im.Pos, "module %q not found", im.Path))
+	}
+	i.env.define(im.Name(), m)
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package interp
+
+import (
+	"fmt"
+	"zimlit/graphene/ast"
+)
+
+type cell struct {
+	value any
+}
+
+type Environment struct {
+	values    map[string]*cell
+	enclosing *Environment
+}
+
+func newEnvironment(enclosing *Environment) *Environment {
+	return &Environment{
+		values:    make(map[string]*cell),
+		enclosing: enclosing,
+	}
+}
+
+func (e *Environment) define(name string, value any) *cell {
+	c := &cell{value}
+	e.values[name] = c
+	return c
+}
+
+func (e *Environment) lookup(name string) *cell {
+	for env := e; env != nil; env = env.enclosing {
+		if c, ok := env.values[name]; ok {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// source is the file an interpreter runs, closures keep the source they were
+// created in to report runtime errors in imported fns against the right file
+type source struct {
+	lines []string
+	fname string
+}
+
+func (s *source) lineStr(line int) string {
+	if line > 0 && line <= len(s.lines) {
+		return s.lines[line-1]
+	}
+	return ""
+}
+
+// Closure is a function value. upvalues holds the cells of the captured
+// variables, cells of ByRef captures are shared with the defining scope
+type Closure struct {
+	Fn       ast.FnExpr
+	name     string
+	upvalues map[string]*cell
+	globals  *Environment
+	src      *source
+}
+
+// Name returns the name the fn was declared with
+func (c *Closure) Name() string {
+	if c.name == "" {
+		return "<anonymous fn>"
+	}
+	return c.name
+}
+
+func (c *Closure) String() string {
+	return fmt.Sprintf("<fn %s>", ast.NewFnT(c.Fn.TypeParams, c.Fn.Params, c.Fn.Rtype))
+}
+
+// Module holds the cells of the top level pub bindings of an interpreted file
+type Module struct {
+	Path    string
+	exports map[string]*cell
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.Path)
+}
@@ -14,60 +14,58 @@ package lexer
 import (
 	"fmt"
 	"strings"
-
-	"github.com/fatih/color"
+	"zimlit/graphene/diag"
 )
 
-type tmpLexErr struct {
-	col   int
-	line  int
-	msg   string
-	fname string
-}
-
-func (l *tmpLexErr) Error() string { return "" }
-
 type LexErr struct {
-	col     int
-	line    int
-	lineStr string
-	msg     string
-	fname   string
+	code diag.Code
+	col  int
+	// endCol is the column just past the source the error is about
+	endCol int
+	line   int
+	lines  []string
+	msg    string
+	fname  string
 }
 
 func (l *LexErr) Error() string {
-	r := color.New(color.FgHiRed, color.Bold).FprintfFunc()
-	w := color.New(color.FgHiWhite, color.Bold).FprintfFunc()
-	b := color.New(color.FgHiBlue, color.Bold).FprintfFunc()
-	var str strings.Builder
-	r(&str, "error")
-	fmt.Fprint(&str, ": ")
-	w(&str, "%s\n", l.msg)
-	b(&str, " --> ")
-	fmt.Fprintf(&str, "%s:%d:%d\n", l.fname, l.line, l.col)
-	b(&str, "  |\n")
-	b(&str, "%d | ", l.line)
-	if l.lineStr[0] == '\n' {
-		fmt.Fprint(&str, l.lineStr[1:])
-		l.col--
-	} else {
-		fmt.Fprint(&str, l.lineStr)
-	}
-	if l.lineStr[len(l.lineStr)-1] != '\n' {
-		fmt.Fprint(&str, "\n")
-	}
+	return l.diagnostic().String()
+}
 
-	b(&str, "  |")
-	for i := 0; i < l.col; i++ {
-		fmt.Fprint(&str, " ")
+func (l *LexErr) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{l.diagnostic()}
+}
+
+func (l *LexErr) diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity: "error",
+		Code:     l.code,
+		Msg:      l.msg,
+		File:     l.fname,
+		Lines:    l.lines,
+		Labels: []diag.Label{{
+			Span:    diag.Span{Line: l.line, Col: l.col, EndCol: l.endCol},
+			Msg:     l.msg,
+			Primary: true,
+		}},
 	}
-	r(&str, "^ %s\n", l.msg)
+}
 
-	return str.String()
+// Position returns the line and column of the error
+func (l *LexErr) Position() (line int, col int) {
+	return l.line, l.col
 }
 
 type LexErrs []LexErr
 
+func (l *LexErrs) Diagnostics() []diag.Diagnostic {
+	var ds []diag.Diagnostic
+	for i := range *l {
+		ds = append(ds, (*l)[i].diagnostic())
+	}
+	return ds
+}
+
 func (l *LexErrs) Error() string {
 	var str strings.Builder
 	for _, err := range *l {
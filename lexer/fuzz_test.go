@@ -0,0 +1,85 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package lexer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zimlit/graphene/internal/srctest"
+)
+
+// seed adds the golden test sources to the corpus of f
+func seed(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*.gr"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(buf))
+	}
+	f.Add("else")
+	f.Add("else i")
+	f.Add("\n\n\"")
+	f.Add("\"\\")
+}
+
+func FuzzLex(f *testing.F) {
+	seed(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			l := NewLexer(src, "fuzz.gr")
+			toks, _, errs := l.Lex()
+			lines := splitLines(src)
+			for _, tok := range toks {
+				if !srctest.InSource(lines, tok.Line, tok.Col) {
+					t.Errorf("token %q at %d:%d is outside of the source", tok.Literal, tok.Line, tok.Col)
+				}
+			}
+			for _, err := range errs {
+				if !srctest.InSource(lines, err.line, err.col) {
+					t.Errorf("error %q at %d:%d is outside of the source", err.msg, err.line, err.col)
+				}
+			}
+			if errs != nil {
+				_ = errs.Error()
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Lex did not finish")
+		}
+	})
+}
+
+func FuzzLexErr(f *testing.F) {
+	f.Add("Unclosed string", "", 1, 1)
+	f.Add("Unexpected character '$'", "let x = $\n", 1, 9)
+	f.Add("", "\n", 0, -1)
+	f.Fuzz(func(t *testing.T, msg string, lineStr string, line int, col int) {
+		if col > 1<<16 {
+			t.Skip()
+		}
+		err := LexErr{col: col, endCol: col + len(msg), line: line, lines: []string{lineStr}, msg: msg, fname: "fuzz.gr"}
+		_ = err.Error()
+	})
+}
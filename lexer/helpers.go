@@ -12,34 +12,30 @@
 
 package lexer
 
-import "zimlit/graphene/token"
-
-func (l *Lexer) newTmpErr(msg string) tmpLexErr {
-	return tmpLexErr{
-		col:   l.col,
-		line:  l.line,
-		msg:   msg,
-		fname: l.fname,
-	}
-}
-
-func (l *Lexer) newTmpErrAt(msg string, col int) tmpLexErr {
-	return tmpLexErr{
-		col:   col,
-		line:  l.line,
-		msg:   msg,
-		fname: l.fname,
+import (
+	"unicode"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/token"
+)
+
+// errorAt returns an error about the current line from col up to endCol,
+// its source is taken from the line table
+func (l *Lexer) errorAt(code diag.Code, msg string, col int, endCol int) LexErr {
+	return LexErr{
+		code:   code,
+		col:    col,
+		endCol: endCol,
+		line:   l.line,
+		msg:    msg,
+		lines:  l.lines,
+		fname:  l.fname,
 	}
 }
 
-func (l *Lexer) newLexErr(tmp tmpLexErr) LexErr {
-	return LexErr{
-		col:     tmp.col,
-		line:    tmp.line,
-		msg:     tmp.msg,
-		lineStr: l.lineStr,
-		fname:   tmp.fname,
-	}
+// illegal returns an illegal token of the source from start to the current
+// position
+func (l *Lexer) illegal(start int, col int) token.Token {
+	return l.newTokenAt(string(l.source[start:l.pos+1]), token.ILLEGAL, col)
 }
 
 func (l *Lexer) newToken(literal string, kind token.TokenKind) token.Token {
@@ -68,21 +64,32 @@ func (l *Lexer) peek() rune {
 }
 
 func (l *Lexer) peekNext() rune {
-	if l.pos < len(l.source)-1 {
-		return l.source[l.pos+1]
+	return l.at(1)
+}
+
+// at returns the rune n places after the current one, or '\000' past the
+// end of the source
+func (l *Lexer) at(n int) rune {
+	if l.pos+n < len(l.source) {
+		return l.source[l.pos+n]
 	}
 	return '\000'
 }
 
-func (l *Lexer) advance() {
-	if l.pos < len(l.source) {
-		l.lineStr += string(l.source[l.pos])
+// atLineEnd reports whether the current rune is the last one on its line
+func (l *Lexer) atLineEnd() bool {
+	return l.pos+1 >= len(l.source) || l.source[l.pos+1] == '\n'
+}
 
-	}
+func (l *Lexer) advance() {
 	l.pos++
 	l.col++
 }
 
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
 func (l *Lexer) match(c rune) bool {
 	if l.peekNext() == c {
 		l.advance()
@@ -91,3 +98,19 @@ func (l *Lexer) match(c rune) bool {
 
 	return false
 }
+
+// endsStmt reports whether a newline after toks ends a statement, which it
+// does when the last token on the line can end one. The newline is then
+// lexed as a semicolon
+func endsStmt(toks []token.Token) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	switch toks[len(toks)-1].Kind {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.NIL,
+		token.INTK, token.FLOATK, token.STRINGK, token.RPAREN, token.RBRACKET, token.END,
+		token.ILLEGAL:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"zimlit/graphene/token"
+)
+
+// Edit replaces the text from the start position up to the end position
+// with Text. Lines and columns count runes from 1 as in tokens, the end
+// column is the first one that is kept
+type Edit struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	Text      string
+}
+
+// Apply returns the lines of the source made by applying e to lines, along
+// with the range [first, last] of edited lines in lines and the number of
+// lines that replace them. The edit may start and end on the line after the
+// last one, columns past the end of a line are its end
+func (e Edit) Apply(lines []string) (edited []string, first int, last int, n int, err error) {
+	if err = e.check(lines); err != nil {
+		return nil, 0, 0, 0, err
+	}
+	line := func(i int) []rune {
+		if i > len(lines) {
+			return nil
+		}
+		return []rune(lines[i-1])
+	}
+	clamp := func(col int, l []rune) int {
+		if col < 1 {
+			return 0
+		}
+		if col-1 > len(l) {
+			return len(l)
+		}
+		return col - 1
+	}
+
+	start, end := line(e.StartLine), line(e.EndLine)
+	text := string(start[:clamp(e.StartCol, start)]) + e.Text + string(end[clamp(e.EndCol, end):])
+	middle := splitLines(text)
+	if text == "" {
+		middle = nil
+	}
+	first, last = e.StartLine, e.EndLine
+	if last > len(lines) {
+		last = len(lines)
+	}
+
+	edited = append(edited, lines[:first-1]...)
+	edited = append(edited, middle...)
+	edited = append(edited, lines[last:]...)
+	return edited, first, last, len(middle), nil
+}
+
+// check returns an error if e does not fit in lines or ends before it starts
+func (e Edit) check(lines []string) error {
+	for _, l := range []int{e.StartLine, e.EndLine} {
+		if l < 1 || l > len(lines)+1 {
+			return fmt.Errorf("edit %d:%d-%d:%d is out of range, the source has %d lines", e.StartLine, e.StartCol, e.EndLine, e.EndCol, len(lines))
+		}
+	}
+	if e.EndLine < e.StartLine || e.EndLine == e.StartLine && e.EndCol < e.StartCol {
+		return fmt.Errorf("edit %d:%d-%d:%d ends before it starts", e.StartLine, e.StartCol, e.EndLine, e.EndCol)
+	}
+	return nil
+}
+
+// Relex lexes the source made by applying e to the source of toks and
+// lines, which must have lexed without errors, and returns its tokens, lines
+// and errors. The tokens of a line only depend on the text of that line, so only
+// the edited lines are lexed again and the tokens after them are moved to
+// their new line. It returns the error of Apply if e is out of range
+func Relex(toks []token.Token, lines []string, e Edit, fname string) ([]token.Token, []string, LexErrs, error) {
+	edited, first, last, n, err := e.Apply(lines)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	delta := n - (last - first + 1)
+
+	l := NewLexer(strings.Join(edited[first-1:first-1+n], ""), fname)
+	l.line, l.lines = first, edited
+	middle, _, errs := l.Lex()
+
+	var relexed []token.Token
+	i := 0
+	for ; i < len(toks) && toks[i].Line < first; i++ {
+		relexed = append(relexed, toks[i])
+	}
+	relexed = append(relexed, middle...)
+	for ; i < len(toks); i++ {
+		if toks[i].Line > last {
+			t := toks[i]
+			t.Line += delta
+			relexed = append(relexed, t)
+		}
+	}
+	if relexed == nil {
+		relexed = []token.Token{}
+	}
+
+	return relexed, edited, errs, nil
+}
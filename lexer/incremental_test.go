@@ -0,0 +1,124 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package lexer
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"zimlit/graphene/internal/srctest"
+)
+
+// randomEdit returns a random edit of src and the source it produces
+func randomEdit(r *rand.Rand, src string) (Edit, string) {
+	e, edited := srctest.RandomEdit(r, src)
+	return Edit(e), edited
+}
+
+func sources(t *testing.T) []string {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "*.gr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := []string{"", "x", "x\n", "let a = 1\nlet b = 2\n"}
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, string(buf))
+	}
+	return srcs
+}
+
+func TestApply(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, src := range sources(t) {
+		for i := 0; i < 200; i++ {
+			e, want := randomEdit(r, src)
+			edited, _, _, _, err := e.Apply(splitLines(src))
+			if err != nil {
+				t.Fatalf("applying %+v to %q: %v", e, src, err)
+			}
+			if got := strings.Join(edited, ""); got != want {
+				t.Fatalf("applying %+v to %q gave %q, want %q", e, src, got, want)
+			}
+		}
+	}
+}
+
+func TestApplyOutOfRange(t *testing.T) {
+	l := NewLexer("let a = 1\nlet b = 2\n", "test.gr")
+	toks, lines, _ := l.Lex()
+	tests := []struct {
+		edit Edit
+		err  string
+	}{
+		{Edit{StartLine: 0, StartCol: 1, EndLine: 1, EndCol: 1}, "out of range"},
+		{Edit{StartLine: -3, StartCol: 1, EndLine: -3, EndCol: 1}, "out of range"},
+		{Edit{StartLine: 4, StartCol: 1, EndLine: 4, EndCol: 1}, "out of range"},
+		{Edit{StartLine: 1, StartCol: 1, EndLine: 5, EndCol: 1}, "out of range"},
+		{Edit{StartLine: 2, StartCol: 1, EndLine: 1, EndCol: 1}, "ends before it starts"},
+		{Edit{StartLine: 1, StartCol: 5, EndLine: 1, EndCol: 2}, "ends before it starts"},
+	}
+	for _, tt := range tests {
+		if _, _, _, _, err := tt.edit.Apply(lines); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("applying %+v gave error %v, want %q", tt.edit, err, tt.err)
+		}
+		if _, _, _, err := Relex(toks, lines, tt.edit, "test.gr"); err == nil {
+			t.Errorf("relexing after %+v gave no error", tt.edit)
+		}
+	}
+
+	// the line after the last one is the end of the source
+	e := Edit{StartLine: 3, StartCol: 1, EndLine: 3, EndCol: 1, Text: "x"}
+	edited, _, _, _, err := e.Apply(lines)
+	if got := strings.Join(edited, ""); err != nil || got != "let a = 1\nlet b = 2\nx" {
+		t.Errorf("appending gave %q, %v", got, err)
+	}
+}
+
+// TestRelex checks that relexing chains of random edits gives the tokens,
+// lines and errors of lexing the edited source from scratch
+func TestRelex(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, src := range sources(t) {
+		l := NewLexer(src, "test.gr")
+		toks, lines, errs := l.Lex()
+		if errs != nil {
+			continue
+		}
+		for i := 0; i < 200; i++ {
+			e, edited := randomEdit(r, src)
+			l := NewLexer(edited, "test.gr")
+			wantToks, wantLines, wantErrs := l.Lex()
+			gotToks, gotLines, gotErrs, err := Relex(toks, lines, e, "test.gr")
+			if err != nil {
+				t.Fatalf("relexing %q after %+v: %v", src, e, err)
+			}
+
+			if !reflect.DeepEqual(gotErrs, wantErrs) {
+				t.Fatalf("relexing %q after %+v gave errors\n%v\nwant\n%v", src, e, gotErrs, wantErrs)
+			}
+			if !reflect.DeepEqual(gotToks, wantToks) || !reflect.DeepEqual(gotLines, wantLines) {
+				t.Fatalf("relexing %q after %+v gave\n%v %q\nwant\n%v %q", src, e, gotToks, gotLines, wantToks, wantLines)
+			}
+			if wantErrs == nil {
+				src, toks, lines = edited, gotToks, gotLines
+			}
+		}
+	}
+}
@@ -14,98 +14,125 @@ package lexer
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"unicode"
+	"zimlit/graphene/diag"
 	"zimlit/graphene/token"
 )
 
+var keywords = map[string]token.TokenKind{
+	"int":    token.INTK,
+	"float":  token.FLOATK,
+	"let":    token.LET,
+	"nil":    token.NIL,
+	"if":     token.IF,
+	"end":    token.END,
+	"else":   token.ELSE,
+	"mut":    token.MUT,
+	"while":  token.WHILE,
+	"string": token.STRINGK,
+	"fn":     token.FN,
+	"return": token.RETURN,
+	"import": token.IMPORT,
+	"pub":    token.PUB,
+}
+
+// Keywords returns the reserved words of the language in alphabetical order
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 type Lexer struct {
-	col      int
-	pos      int
-	line     int
-	lineStr  string
-	source   []rune
-	fname    string
-	keywords map[string]token.TokenKind
+	col    int
+	pos    int
+	line   int
+	lines  []string
+	source []rune
+	fname  string
+	spans  []Span
+}
+
+// Span is the range [Start, End) of runes of the source a token was lexed
+// from
+type Span struct {
+	Start int
+	End   int
 }
 
 func NewLexer(source string, fname string) Lexer {
-	l := Lexer{
-		col:     1,
-		pos:     0,
-		line:    1,
-		lineStr: "",
-		source:  []rune(source),
-		fname:   fname,
+	return Lexer{
+		col:    1,
+		pos:    0,
+		line:   1,
+		lines:  splitLines(source),
+		source: []rune(source),
+		fname:  fname,
 	}
-	l.keywords = make(map[string]token.TokenKind)
-	l.keywords["int"] = token.INTK
-	l.keywords["float"] = token.FLOATK
-	l.keywords["let"] = token.LET
-	l.keywords["nil"] = token.NIL
-	l.keywords["if"] = token.IF
-	l.keywords["end"] = token.END
-	l.keywords["else"] = token.ELSE
-	l.keywords["mut"] = token.MUT
-	l.keywords["while"] = token.WHILE
-	l.keywords["string"] = token.STRINGK
-	l.keywords["fn"] = token.FN
-	l.keywords["return"] = token.RETURN
-
-	return l
 }
 
-func (l *Lexer) string() (*token.Token, *tmpLexErr) {
-	val := ""
+// string lexes a string literal, stopping on its closing quote. An
+// unclosed string ends at the end of its line. A string with an error is an
+// illegal token
+func (l *Lexer) string() (token.Token, *LexErr) {
+	var val strings.Builder
+	start := l.pos
 	col := l.col
-	l.advance()
+	var escErr *LexErr
 
-Exit:
-	for ; ; l.advance() {
-		if l.pos >= len(l.source) {
-			err := l.newTmpErr("Unclosed string")
-			return nil, &err
+	for {
+		if l.atLineEnd() {
+			err := l.errorAt(diag.UnclosedString, "Unclosed string", col, l.col+1)
+			return l.illegal(start, col), &err
 		}
+		l.advance()
+
 		switch l.peek() {
 		case '"':
-			break Exit
-		case '\000':
-			fallthrough
-		case '\n':
-			err := l.newTmpErr("Unclosed string")
-			return nil, &err
+			if escErr != nil {
+				return l.illegal(start, col), escErr
+			}
+			return l.newTokenAt(val.String(), token.STRING, col), nil
 		case '\\':
+			if l.atLineEnd() {
+				continue
+			}
 			l.advance()
 			switch l.peek() {
 			case 't':
-				val += "\t"
+				val.WriteString("\t")
 			case 'n':
-				val += "\n"
+				val.WriteString("\n")
 			case '"':
-				val += "\""
+				val.WriteString("\"")
 			case '\\':
-				val += "\\"
+				val.WriteString("\\")
 			case 'r':
-				val += "\r"
+				val.WriteString("\r")
 			case 'v':
-				val += "\v"
+				val.WriteString("\v")
 			default:
-				err := l.newTmpErr("Invalid escape character")
-				return nil, &err
+				if escErr == nil {
+					err := l.errorAt(diag.InvalidEscape, "Invalid escape character", l.col-1, l.col+1)
+					escErr = &err
+				}
 			}
 		default:
-			val += string(l.peek())
+			val.WriteRune(l.peek())
 		}
 	}
-	tok := l.newTokenAt(val, token.STRING, col)
-	return &tok, nil
 }
 
-func (l *Lexer) num() (*token.Token, *tmpLexErr) {
-	val := ""
+func (l *Lexer) num() (token.Token, *LexErr) {
 	dot_count := 0
+	start := l.pos
 	col := l.col
 	for ; l.pos < len(l.source); l.advance() {
-		val += string(l.peek())
 		if l.peek() == '.' {
 			dot_count++
 		}
@@ -115,69 +142,104 @@ func (l *Lexer) num() (*token.Token, *tmpLexErr) {
 		}
 	}
 
-	var tok token.Token
+	val := string(l.source[start : l.pos+1])
 
 	if dot_count > 1 {
-		e := l.newTmpErrAt("to many dots in number literal", col)
-		return nil, &e
+		err := l.errorAt(diag.MalformedNumber, "to many dots in number literal", col, l.col+1)
+		return l.illegal(start, col), &err
 	} else if dot_count == 1 {
-		tok = l.newTokenAt(val, token.FLOAT, col)
-	} else {
-		tok = l.newTokenAt(val, token.INT, col)
+		return l.newTokenAt(val, token.FLOAT, col), nil
 	}
-
-	return &tok, nil
+	return l.newTokenAt(val, token.INT, col), nil
 }
 
 func (l *Lexer) ident() token.Token {
-	val := ""
+	start := l.pos
 	col := l.col
 
 	for ; l.pos < len(l.source); l.advance() {
-		val += string(l.peek())
-
-		if !(unicode.IsLetter(l.peekNext()) || unicode.IsDigit(l.peekNext()) || l.peekNext() == '_') {
+		if !isIdentRune(l.peekNext()) {
 			break
 		}
 	}
-	if val == "else" {
-		if l.source[l.pos+2] == 'i' {
-			if l.source[l.pos+3] == 'f' {
-				l.advance()
-				l.advance()
-				l.advance()
-				return l.newTokenAt(val, token.ELSEIF, col)
-			}
-		}
+	val := string(l.source[start : l.pos+1])
+	if val == "else" && (l.at(1) == ' ' || l.at(1) == '\t') && l.at(2) == 'i' && l.at(3) == 'f' && !isIdentRune(l.at(4)) {
+		l.advance()
+		l.advance()
+		l.advance()
+		return l.newTokenAt(val, token.ELSEIF, col)
 	}
-	t := l.keywords[val]
+	t := keywords[val]
 	if t == 0 {
 		return l.newTokenAt(val, token.IDENT, col)
 	}
 	return l.newTokenAt(val, t, col)
 }
 
+// Spans returns the span of each token returned by Lex
+func (l *Lexer) Spans() []Span {
+	return l.spans
+}
+
+// Lex returns the tokens and the lines of the source. Text that is not a
+// valid token is reported as an error and lexed as an illegal token, so the
+// tokens are returned along with the errors
 func (l *Lexer) Lex() ([]token.Token, []string, LexErrs) {
 	toks := []token.Token{}
 	var errs LexErrs = nil
-	tmps := []tmpLexErr{}
-	lines := []string{}
 	for ; l.pos < len(l.source); l.advance() {
+		start, n := l.pos, len(toks)
 		switch l.peek() {
 		case '+':
-			toks = append(toks, l.newToken("+", token.PLUS))
+			if l.match('=') {
+				toks = append(toks, l.newTokenAt("+=", token.PLUSEQ, l.col-1))
+			} else {
+				toks = append(toks, l.newToken("+", token.PLUS))
+			}
 		case '-':
-			toks = append(toks, l.newToken("-", token.MINUS))
+			if l.match('=') {
+				toks = append(toks, l.newTokenAt("-=", token.MINUSEQ, l.col-1))
+			} else {
+				toks = append(toks, l.newToken("-", token.MINUS))
+			}
 		case '*':
-			toks = append(toks, l.newToken("*", token.STAR))
+			if l.match('=') {
+				toks = append(toks, l.newTokenAt("*=", token.STAREQ, l.col-1))
+			} else {
+				toks = append(toks, l.newToken("*", token.STAR))
+			}
 		case '/':
-			toks = append(toks, l.newToken("/", token.SLASH))
+			if l.match('=') {
+				toks = append(toks, l.newTokenAt("/=", token.SLASHEQ, l.col-1))
+			} else {
+				toks = append(toks, l.newToken("/", token.SLASH))
+			}
+		case '%':
+			if l.match('=') {
+				toks = append(toks, l.newTokenAt("%=", token.PERCENTEQ, l.col-1))
+			} else {
+				toks = append(toks, l.newToken("%", token.PERCENT))
+			}
+		case '&':
+			toks = append(toks, l.newToken("&", token.AMP))
+		case '|':
+			toks = append(toks, l.newToken("|", token.PIPE))
+		case '^':
+			toks = append(toks, l.newToken("^", token.CARET))
+		case '~':
+			toks = append(toks, l.newToken("~", token.TILDE))
 		case '(':
 			toks = append(toks, l.newToken("(", token.LPAREN))
 		case ')':
 			toks = append(toks, l.newToken(")", token.RPAREN))
+		case '[':
+			toks = append(toks, l.newToken("[", token.LBRACKET))
+		case ']':
+			toks = append(toks, l.newToken("]", token.RBRACKET))
 		case ',':
 			toks = append(toks, l.newToken(",", token.COMMA))
+		case '.':
+			toks = append(toks, l.newToken(".", token.DOT))
 		case '=':
 			if l.match('=') {
 				toks = append(toks, l.newTokenAt("==", token.EQEQ, l.col-1))
@@ -193,67 +255,70 @@ func (l *Lexer) Lex() ([]token.Token, []string, LexErrs) {
 		case '<':
 			if l.match('=') {
 				toks = append(toks, l.newTokenAt("<=", token.LESSEQ, l.col-1))
+			} else if l.match('<') {
+				toks = append(toks, l.newTokenAt("<<", token.SHL, l.col-1))
 			} else {
 				toks = append(toks, l.newToken("<", token.LESS))
 			}
 		case '>':
 			if l.match('=') {
 				toks = append(toks, l.newTokenAt(">=", token.GREATEREQ, l.col-1))
+			} else if l.match('>') {
+				toks = append(toks, l.newTokenAt(">>", token.SHR, l.col-1))
 			} else {
 				toks = append(toks, l.newToken(">", token.GREATER))
 			}
 		case ':':
 			toks = append(toks, l.newToken(":", token.COLON))
+		case ';':
+			toks = append(toks, l.newToken(";", token.SEMICOLON))
 		case '"':
 			t, err := l.string()
+			toks = append(toks, t)
 			if err != nil {
-				tmps = append(tmps, *err)
-			} else {
-				toks = append(toks, *t)
+				errs = append(errs, *err)
 			}
 		case ' ':
 		case '\t':
 		case '\r':
 		case '\v':
 		case '\n':
-			l.lineStr += "\n"
-			for _, err := range tmps {
-				errs = append(errs, l.newLexErr(err))
+			if endsStmt(toks) {
+				toks = append(toks, l.newToken("\n", token.SEMICOLON))
 			}
-			lines = append(lines, l.lineStr)
 			l.line++
-			l.lineStr = ""
-			tmps = []tmpLexErr{}
-			l.col = 1
+			// the column is advanced to 1 along with the position
+			l.col = 0
 		default:
 			if unicode.IsDigit(l.peek()) {
 				t, err := l.num()
-
+				toks = append(toks, t)
 				if err != nil {
-					tmps = append(tmps, *err)
-				} else {
-					toks = append(toks, *t)
+					errs = append(errs, *err)
 				}
-
 			} else if unicode.IsLetter(l.peek()) || l.peek() == '_' {
 				t := l.ident()
 				toks = append(toks, t)
 			} else {
-				tmps = append(tmps, l.newTmpErr(fmt.Sprintf("Unexpected character '%s'", string(l.peek()))))
-
+				toks = append(toks, l.illegal(l.pos, l.col))
+				errs = append(errs, l.errorAt(diag.UnexpectedCharacter, fmt.Sprintf("Unexpected character '%s'", string(l.peek())), l.col, l.col+1))
 			}
 
 		}
+		// every token starts on the rune the iteration started on
+		if len(toks) > n {
+			l.spans = append(l.spans, Span{start, l.pos + 1})
+		}
 	}
-	for _, err := range tmps {
-		errs = append(errs, l.newLexErr(err))
-	}
-	if l.lineStr != "" {
-		lines = append(lines, l.lineStr)
-	}
+	return toks, l.lines, errs
+}
 
-	if errs != nil {
-		return nil, nil, errs
+// splitLines splits source into lines, each ending in its newline except
+// for a last line without one
+func splitLines(source string) []string {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
 	}
-	return toks, lines, nil
+	return lines
 }
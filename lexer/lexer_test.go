@@ -0,0 +1,35 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexReportsAllErrors(t *testing.T) {
+	src := strings.Repeat("let x = $\n", 15)
+	l := NewLexer(src, "test.gr")
+	toks, _, errs := l.Lex()
+	if len(errs) != 15 {
+		t.Errorf("got %d errors, want 15", len(errs))
+	}
+	for i, err := range errs {
+		if line, col := err.Position(); line != i+1 || col != 9 {
+			t.Errorf("error %d is at %d:%d, want %d:9", i, line, col, i+1)
+		}
+	}
+	if len(toks) == 0 {
+		t.Error("got no tokens")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package loader
+
+import (
+	"fmt"
+	"zimlit/graphene/diag"
+)
+
+type LoadErr struct {
+	code  diag.Code
+	msg   string
+	notes []string
+	line  int
+	col   int
+	// endCol is just past the end of the import the error is about
+	endCol int
+	lines  []string
+	fname  string
+}
+
+func (l LoadErr) Error() string {
+	return l.diagnostic().String()
+}
+
+func (l LoadErr) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{l.diagnostic()}
+}
+
+func (l LoadErr) diagnostic() diag.Diagnostic {
+	notes := []diag.Note{}
+	for _, note := range l.notes {
+		notes = append(notes, diag.Note{Kind: "note", Msg: note})
+	}
+	return diag.Diagnostic{
+		Severity: "error",
+		Code:     l.code,
+		Msg:      l.msg,
+		File:     l.fname,
+		Lines:    l.lines,
+		Labels: []diag.Label{{
+			Span:    diag.Span{Line: l.line, Col: l.col, EndCol: l.endCol},
+			Msg:     l.msg,
+			Primary: true,
+		}},
+		Notes: notes,
+	}
+}
+
+func (m *Module) newLoadErr(code diag.Code, line int, col int, notes []string, format string, args ...any) LoadErr {
+	return LoadErr{
+		code:   code,
+		msg:    fmt.Sprintf(format, args...),
+		notes:  notes,
+		line:   line,
+		col:    col,
+		endCol: m.importEnd(line, col),
+		lines:  m.Lines,
+		fname:  m.File,
+	}
+}
+
+// importEnd returns the column just past the path of the import at col
+func (m *Module) importEnd(line int, col int) int {
+	if line < 1 || line > len(m.Lines) || col < 1 {
+		return col + len("import")
+	}
+	src := []rune(m.Lines[line-1])
+	quotes := 0
+	for i := col - 1; i < len(src); i++ {
+		if src[i] == '"' {
+			quotes++
+			if quotes == 2 {
+				return i + 2
+			}
+		}
+	}
+	return col + len("import")
+}
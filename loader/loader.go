@@ -0,0 +1,136 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/checker"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/parser"
+)
+
+const Ext = ".gr"
+
+type Module struct {
+	Path      string
+	File      string
+	Lines     []string
+	Stmts     ast.Stmts
+	Interface checker.Module
+}
+
+// Loader loads a module and everything it imports. Import paths are
+// resolved relative to the project root
+type Loader struct {
+	root    string
+	modules map[string]*Module
+	order   []*Module
+	stack   []*Module
+}
+
+func NewLoader(root string) Loader {
+	return Loader{
+		root:    root,
+		modules: make(map[string]*Module),
+	}
+}
+
+// Project returns the root and the import path of the entry module for the
+// directory or file passed to the command line, a directory is a project
+// whose entry module is main
+func Project(arg string) (root string, path string, err error) {
+	info, err := os.Stat(arg)
+	if err != nil {
+		return "", "", err
+	}
+	if info.IsDir() {
+		return arg, "main", nil
+	}
+	base := filepath.Base(arg)
+	return filepath.Dir(arg), base[:len(base)-len(filepath.Ext(base))], nil
+}
+
+// Modules returns every loaded module, each one after the modules it imports
+func (l *Loader) Modules() []*Module {
+	return l.order
+}
+
+func (l *Loader) Load(path string) (*Module, error) {
+	return l.load(path, nil)
+}
+
+func (l *Loader) load(path string, imp *ast.Import) (*Module, error) {
+	if m, ok := l.modules[path]; ok {
+		return m, nil
+	}
+	for i, m := range l.stack {
+		if m.Path == path {
+			from := l.stack[len(l.stack)-1]
+			notes := []string{}
+			for j, m := range l.stack[i:] {
+				next := path
+				if i+j+1 < len(l.stack) {
+					next = l.stack[i+j+1].Path
+				}
+				notes = append(notes, fmt.Sprintf("%s imports %s", m.Path, next))
+			}
+			return nil, from.newLoadErr(diag.ImportCycle, imp.Pos.Line, imp.Pos.Col, notes, "import cycle not allowed")
+		}
+	}
+
+	m := &Module{
+		Path: path,
+		File: filepath.Join(l.root, filepath.FromSlash(path)+Ext),
+	}
+	buf, err := os.ReadFile(m.File)
+	if err != nil {
+		if imp == nil {
+			return nil, err
+		}
+		from := l.stack[len(l.stack)-1]
+		return nil, from.newLoadErr(diag.ModuleNotFound, imp.Pos.Line, imp.Pos.Col, nil, "cannot find module %q, looked for %s", path, m.File)
+	}
+
+	f, err := parser.ParseSource(context.Background(), m.File, string(buf))
+	if err != nil {
+		return nil, err
+	}
+	m.Lines = f.Lines
+
+	l.stack = append(l.stack, m)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	ch := checker.NewChecker(f.Lines, m.File)
+	for _, stmt := range f.Stmts {
+		if imp, ok := stmt.(ast.Import); ok {
+			dep, err := l.load(imp.Path, &imp)
+			if err != nil {
+				return nil, err
+			}
+			ch.AddModule(dep.Interface)
+		}
+	}
+	m.Stmts, err = ch.Check(f.Stmts)
+	if err != nil {
+		return nil, err
+	}
+	m.Interface = ch.Module(path)
+
+	l.modules[path] = m
+	l.order = append(l.order, m)
+	return m, nil
+}
@@ -0,0 +1,184 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package loader_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/interp"
+	"zimlit/graphene/loader"
+)
+
+// project writes files, by their path relative to the root, to a new
+// project directory and returns its root
+func project(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// run interprets the loaded modules the way graphene run does and returns
+// what they printed
+func run(t *testing.T, l *loader.Loader) string {
+	t.Helper()
+	var out strings.Builder
+	host := &builtins.Host{Stdout: &out}
+	modules := make(map[string]*interp.Module)
+	for _, m := range l.Modules() {
+		in := interp.NewInterpreter(m.Lines, m.File)
+		in.SetHost(host)
+		for _, stmt := range m.Stmts {
+			if imp, ok := stmt.(ast.Import); ok {
+				in.AddModule(modules[imp.Path])
+			}
+		}
+		if _, err := in.Interpret(m.Stmts); err != nil {
+			t.Fatal(err)
+		}
+		modules[m.Path] = in.Module(m.Path)
+	}
+	return out.String()
+}
+
+func TestLoad(t *testing.T) {
+	root := project(t, map[string]string{
+		"main.gr":     "import \"math/ops\"\nimport \"util\"\nprintln(ops.add(1, util.two))\n",
+		"util.gr":     "pub let two: int = 2\n",
+		"math/ops.gr": "import \"util\"\npub fn add(a: int, b: int): int\n\ta + b + util.two\nend\nlet hidden = 3\n",
+	})
+	l := loader.NewLoader(root)
+	if _, err := l.Load("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, m := range l.Modules() {
+		order = append(order, m.Path)
+	}
+	if want := []string{"util", "math/ops", "main"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("loaded %v, want %v", order, want)
+	}
+	ops := l.Modules()[1]
+	if ops.File != filepath.Join(root, "math", "ops.gr") {
+		t.Errorf("math/ops is at %s", ops.File)
+	}
+	exports := ops.Interface.Exports
+	if len(exports) != 1 || exports["add"] == nil || exports["add"].String() != "(fn int (int int))" {
+		t.Errorf("math/ops exports %v", exports)
+	}
+	if got := run(t, &l); got != "5\n" {
+		t.Errorf("printed %q, want %q", got, "5\n")
+	}
+}
+
+func TestProject(t *testing.T) {
+	root := project(t, map[string]string{"main.gr": "1\n", "tool.gr": "2\n"})
+	tests := []struct {
+		arg, root, path string
+	}{
+		{root, root, "main"},
+		{filepath.Join(root, "tool.gr"), root, "tool"},
+	}
+	for _, tt := range tests {
+		root, path, err := loader.Project(tt.arg)
+		if err != nil || root != tt.root || path != tt.path {
+			t.Errorf("Project(%q) = %q, %q, %v, want %q, %q", tt.arg, root, path, err, tt.root, tt.path)
+		}
+	}
+	if _, _, err := loader.Project(filepath.Join(root, "missing.gr")); err == nil {
+		t.Error("found a missing project")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		code  diag.Code
+		msg   string
+		notes []string
+	}{
+		{
+			name:  "not exported",
+			files: map[string]string{"main.gr": "import \"util\"\nutil.hidden\n", "util.gr": "let hidden = 1\n"},
+			code:  diag.NotExported,
+			msg:   "hidden is not exported by module util",
+		},
+		{
+			name:  "module as value",
+			files: map[string]string{"main.gr": "import \"util\"\nprintln(util)\n", "util.gr": "pub let x = 1\n"},
+			code:  diag.ModuleAsValue,
+			msg:   "use of module util without selector",
+		},
+		{
+			name:  "not a module",
+			files: map[string]string{"main.gr": "let x = 1\nx.y\n"},
+			code:  diag.ModuleAsValue,
+			msg:   "x is not a module",
+		},
+		{
+			name:  "missing module",
+			files: map[string]string{"main.gr": "import \"nowhere\"\n"},
+			code:  diag.ModuleNotFound,
+			msg:   "cannot find module \"nowhere\"",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.gr": "import \"a\"\n",
+				"a.gr":    "import \"b\"\n",
+				"b.gr":    "import \"a\"\n",
+			},
+			code:  diag.ImportCycle,
+			msg:   "import cycle not allowed",
+			notes: []string{"a imports b", "b imports a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := loader.NewLoader(project(t, tt.files))
+			_, err := l.Load("main")
+			var derr diag.Error
+			if !errors.As(err, &derr) {
+				t.Fatalf("got %v, want a diagnostic", err)
+			}
+			d := derr.Diagnostics()[0]
+			if d.Code != tt.code || !strings.HasPrefix(d.Msg, tt.msg) {
+				t.Errorf("got %s %q, want %s %q", d.Code, d.Msg, tt.code, tt.msg)
+			}
+			var notes []string
+			for _, n := range d.Notes {
+				notes = append(notes, n.Msg)
+			}
+			if !reflect.DeepEqual(notes, tt.notes) {
+				t.Errorf("got notes %q, want %q", notes, tt.notes)
+			}
+		})
+	}
+}
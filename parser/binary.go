@@ -14,95 +14,197 @@ package parser
 
 import (
 	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
 	"zimlit/graphene/token"
 )
 
-func (p *Parser) assignment() (ast.Expr, error) {
-	if p.match(token.IDENT) {
-		ident := p.previous()
-		c, _ := p.consume(token.EQ)
-		if !c {
-			p.pos--
-			return p.equality()
-		}
-		val, err := p.expression()
-		if err != nil {
-			return nil, err
-		}
+// binding powers, an operator binds its operands tighter than every
+// operator with a lower power
+const (
+	_ = iota
+	ASSIGNMENT
+	EQUALITY
+	COMPARISON
+	TERM
+	FACTOR
+	UNARY
+	POSTFIX
+)
+
+type assoc uint8
+
+const (
+	leftAssoc assoc = iota
+	rightAssoc
+)
+
+// infixFn parses the rest of an infix or postfix expression after op has
+// been consumed, power is the binding power to parse a right operand with
+type infixFn func(p *Parser, left ast.Expr, op *token.Token, power int) (ast.Expr, error)
+
+// prefixFn parses the rest of a prefix expression after op has been consumed
+type prefixFn func(p *Parser, op *token.Token, power int) (ast.Expr, error)
+
+type infix struct {
+	power int
+	assoc assoc
+	parse infixFn
+}
 
-		return ast.NewAssignment(ident.Literal, val), nil
+type prefix struct {
+	power int
+	parse prefixFn
+}
+
+var infixes = make(map[token.TokenKind]infix)
+var prefixes = make(map[token.TokenKind]prefix)
+
+func registerInfix(power int, a assoc, parse infixFn, kinds ...token.TokenKind) {
+	for _, kind := range kinds {
+		infixes[kind] = infix{power, a, parse}
+	}
+}
+
+func registerPrefix(power int, parse prefixFn, kinds ...token.TokenKind) {
+	for _, kind := range kinds {
+		prefixes[kind] = prefix{power, parse}
 	}
+}
 
-	return p.equality()
+func init() {
+	registerInfix(ASSIGNMENT, rightAssoc, (*Parser).assign, token.EQ, token.PLUSEQ, token.MINUSEQ, token.STAREQ, token.SLASHEQ, token.PERCENTEQ)
+	registerInfix(EQUALITY, leftAssoc, (*Parser).binaryOp, token.EQEQ, token.NEQ)
+	registerInfix(COMPARISON, leftAssoc, (*Parser).binaryOp, token.LESS, token.LESSEQ, token.GREATER, token.GREATEREQ)
+	registerInfix(TERM, leftAssoc, (*Parser).binaryOp, token.PLUS, token.MINUS, token.PIPE, token.CARET)
+	registerInfix(FACTOR, leftAssoc, (*Parser).binaryOp, token.STAR, token.SLASH, token.PERCENT, token.AMP, token.SHL, token.SHR)
+	registerInfix(POSTFIX, leftAssoc, (*Parser).finishCall, token.LPAREN)
+	registerInfix(POSTFIX, leftAssoc, (*Parser).instantiate, token.LBRACKET)
+	registerInfix(POSTFIX, leftAssoc, (*Parser).get, token.DOT)
+
+	registerPrefix(UNARY, (*Parser).unary, token.MINUS, token.BANG, token.TILDE)
 }
 
-func (p *Parser) equality() (ast.Expr, error) {
-	expr, err := p.comparison()
+// binary parses an expression made of operators binding tighter than power
+func (p *Parser) binary(power int) (ast.Expr, error) {
+	var left ast.Expr
+	var err error
+	if t := p.peek(); t != nil && prefixes[t.Kind].parse != nil {
+		pre := prefixes[t.Kind]
+		p.advance()
+		left, err = pre.parse(p, p.previous(), pre.power)
+	} else {
+		left, err = p.primary()
+	}
 	if err != nil {
 		return nil, err
 	}
-	for p.match(token.EQEQ, token.NEQ) {
-		operator := p.previous()
-		right, err := p.comparison()
+
+	for {
+		t := p.peek()
+		if t == nil {
+			break
+		}
+		op, ok := infixes[t.Kind]
+		if !ok || op.power <= power {
+			break
+		}
+		p.advance()
+		rpower := op.power
+		if op.assoc == rightAssoc {
+			rpower--
+		}
+		left, err = op.parse(p, left, p.previous(), rpower)
 		if err != nil {
 			return nil, err
 		}
-		expr = ast.NewBinary(expr, *operator, right)
 	}
 
-	return expr, nil
+	return left, nil
 }
 
-func (p *Parser) comparison() (ast.Expr, error) {
-	expr, err := p.term()
+func (p *Parser) unary(op *token.Token, power int) (ast.Expr, error) {
+	right, err := p.binary(power - 1)
 	if err != nil {
 		return nil, err
 	}
+	return ast.NewUnary(*op, right), nil
+}
 
-	for p.match(token.LESS, token.LESSEQ, token.GREATER, token.GREATEREQ) {
-		operator := p.previous()
-		right, err := p.term()
-		if err != nil {
-			return nil, err
-		}
-		expr = ast.NewBinary(expr, *operator, right)
+func (p *Parser) binaryOp(left ast.Expr, op *token.Token, power int) (ast.Expr, error) {
+	right, err := p.binary(power)
+	if err != nil {
+		return nil, err
 	}
+	return ast.NewBinary(left, *op, right), nil
+}
 
-	return expr, nil
+// compound maps compound assignment operators to the binary operator they
+// apply, x += 1 is parsed as x = x + 1
+var compound = map[token.TokenKind]token.TokenKind{
+	token.PLUSEQ:    token.PLUS,
+	token.MINUSEQ:   token.MINUS,
+	token.STAREQ:    token.STAR,
+	token.SLASHEQ:   token.SLASH,
+	token.PERCENTEQ: token.PERCENT,
 }
 
-func (p *Parser) term() (ast.Expr, error) {
-	expr, err := p.factor()
+func (p *Parser) assign(left ast.Expr, op *token.Token, power int) (ast.Expr, error) {
+	target, ok := left.(ast.Literal)
+	if !ok || target.Kind != token.IDENT {
+		return nil, p.errAt(op, diag.InvalidAssignment, "Invalid assignment target")
+	}
+	val, err := p.binary(power)
 	if err != nil {
 		return nil, err
 	}
+	if kind, ok := compound[op.Kind]; ok {
+		operator := token.Token{
+			Kind:    kind,
+			Literal: kind.String(),
+			Line:    op.Line,
+			Col:     op.Col,
+		}
+		val = ast.NewBinary(target, operator, val)
+	}
 
-	for p.match(token.MINUS, token.PLUS) {
-		operator := p.previous()
-		right, err := p.factor()
-		if err != nil {
-			return nil, err
+	return ast.NewAssignment(target.Value, val, target.Pos), nil
+}
+
+func (p *Parser) finishCall(callee ast.Expr, open *token.Token, _ int) (ast.Expr, error) {
+	args := []ast.Expr{}
+	if !p.check(token.RPAREN) {
+		for {
+			x, err := p.expression()
+			if err != nil {
+				return nil, p.unclosed(err, open, token.RPAREN)
+			}
+			args = append(args, x)
+			if !p.match(token.COMMA) {
+				break
+			}
 		}
-		expr = ast.NewBinary(expr, *operator, right)
 	}
 
-	return expr, nil
+	_, err := p.consume(token.RPAREN)
+	if err != nil {
+		return nil, p.unclosed(err, open, token.RPAREN)
+	}
+
+	return ast.NewCall(callee, args), nil
 }
 
-func (p *Parser) factor() (ast.Expr, error) {
-	expr, err := p.unary()
+func (p *Parser) instantiate(fn ast.Expr, op *token.Token, _ int) (ast.Expr, error) {
+	typeArgs, err := p.kinds(token.RBRACKET)
 	if err != nil {
 		return nil, err
 	}
+	return ast.NewInstantiate(fn, typeArgs, posOf(op)), nil
+}
 
-	for p.match(token.SLASH, token.STAR) {
-		operator := p.previous()
-		right, err := p.unary()
-		if err != nil {
-			return nil, err
-		}
-		expr = ast.NewBinary(expr, *operator, right)
+func (p *Parser) get(object ast.Expr, op *token.Token, _ int) (ast.Expr, error) {
+	_, err := p.consume(token.IDENT)
+	if err != nil {
+		return nil, err
 	}
-
-	return expr, nil
+	return ast.NewGet(object, p.previous().Literal, posOf(op)), nil
 }
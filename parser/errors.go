@@ -14,20 +14,19 @@ package parser
 
 import (
 	"fmt"
+	"sort"
 	"strings"
-	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/lexer"
 	"zimlit/graphene/token"
-
-	"github.com/fatih/color"
 )
 
-type ParseResult struct {
-	Exprs ast.Exprs
-	Err   ParseError
-}
-
 type ParseError []error
 
+func (p ParseError) Diagnostics() []diag.Diagnostic {
+	return diag.Collect(p)
+}
+
 func (p ParseError) Error() string {
 	var str strings.Builder
 
@@ -38,52 +37,154 @@ func (p ParseError) Error() string {
 	return str.String()
 }
 
-type MsgErr struct {
-	msg     string
-	line    int
-	col     int
-	lineStr string
-	fname   string
+// JoinErrors reports the lexical errors of a source along with err, the
+// result of parsing its tokens, in source order. A syntax error on a line
+// with a lexical error is usually caused by the illegal token and is left
+// out. Every lexical error is reported, syntax errors fill what is left of
+// maxErrs and the ones past it are counted by a last note
+func JoinErrors(lexErrs lexer.LexErrs, err error) error {
+	if lexErrs == nil {
+		return err
+	}
+	syntax, ok := err.(ParseError)
+	if err != nil && !ok {
+		return err
+	}
+
+	bad := make(map[int]bool)
+	var errs ParseError
+	for i := range lexErrs {
+		line, _ := lexErrs[i].Position()
+		bad[line] = true
+		errs = append(errs, &lexErrs[i])
+	}
+	more := 0
+	for _, err := range syntax {
+		if line, _ := errPosition(err); bad[line] {
+			continue
+		}
+		if len(errs) >= maxErrs {
+			more++
+			continue
+		}
+		errs = append(errs, err)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		li, ci := errPosition(errs[i])
+		lj, cj := errPosition(errs[j])
+		return li < lj || (li == lj && ci < cj)
+	})
+	if more > 0 {
+		errs = append(errs, moreErrs(more))
+	}
+	return errs
 }
 
-func (m MsgErr) Error() string {
-	var str strings.Builder
-	r := color.New(color.FgHiRed, color.Bold).FprintfFunc()
-	w := color.New(color.FgHiWhite, color.Bold).FprintfFunc()
-	b := color.New(color.FgHiBlue, color.Bold).FprintfFunc()
-
-	r(&str, "error")
-	fmt.Fprint(&str, ": ")
-	w(&str, "%s\n", m.msg)
-	b(&str, " --> ")
-	fmt.Fprintf(&str, "%s:%d:%d\n", m.fname, m.line, m.col)
-	b(&str, "  |\n")
-	b(&str, "%d | ", m.line)
-	if m.lineStr[0] == '\n' {
-		fmt.Fprint(&str, m.lineStr[1:])
-		m.col--
-	} else {
-		fmt.Fprint(&str, m.lineStr)
+// moreErrs is the number of syntax errors left out of a ParseError
+type moreErrs int
+
+func (m moreErrs) Error() string {
+	return m.diagnostic().String()
+}
+
+func (m moreErrs) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{m.diagnostic()}
+}
+
+func (m moreErrs) diagnostic() diag.Diagnostic {
+	msg := fmt.Sprintf("%d more syntax errors not shown", int(m))
+	if m == 1 {
+		msg = "1 more syntax error not shown"
+	}
+	return diag.Diagnostic{Severity: "note", Msg: msg}
+}
+
+// errPosition returns the line and column of an error reported by the lexer or
+// the parser
+func errPosition(err error) (line int, col int) {
+	switch err := err.(type) {
+	case *lexer.LexErr:
+		return err.Position()
+	case MsgErr:
+		return err.line, err.col
+	case UnexpectedTokenErr:
+		return err.line, err.col
 	}
-	if m.lineStr[len(m.lineStr)-1] != '\n' {
-		fmt.Fprint(&str, "\n")
+	return 0, 0
+}
+
+// Incomplete reports whether err is a parse error caused only by the source
+// ending before the construct being parsed, such as a block without its end
+// or an unclosed paren. More input may make such a source valid. An unclosed
+// string is not incomplete, strings cannot span lines so no further line
+// could close it
+func Incomplete(err error) bool {
+	errs, ok := err.(ParseError)
+	if !ok || len(errs) == 0 {
+		return false
 	}
-	b(&str, "  |")
-	for i := 0; i < m.col; i++ {
-		fmt.Fprint(&str, " ")
+	for _, err := range errs {
+		switch err := err.(type) {
+		case MsgErr:
+			if !err.eof {
+				return false
+			}
+		case UnexpectedTokenErr:
+			if err.got != nil {
+				return false
+			}
+		default:
+			return false
+		}
 	}
-	r(&str, "^ %s\n", m.msg)
+	return true
+}
 
-	return str.String()
+type MsgErr struct {
+	code diag.Code
+	msg  string
+	line int
+	col  int
+	// endCol is the column just past the token the error is at
+	endCol int
+	lines  []string
+	fname  string
+	// labels point at other tokens the error is about
+	labels []diag.Label
+	notes  []diag.Note
+	// eof is set when the source ended where the error occurred
+	eof bool
 }
 
-func newMsgErr(msg string, line int, col int, lineStr string, fname string) MsgErr {
+func (m MsgErr) Error() string {
+	return m.diagnostic().String()
+}
+
+func (m MsgErr) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{m.diagnostic()}
+}
+
+func (m MsgErr) diagnostic() diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity: "error",
+		Code:     m.code,
+		Msg:      m.msg,
+		File:     m.fname,
+		Lines:    m.lines,
+		Labels:   append([]diag.Label{primary(m.line, m.col, m.endCol, m.msg)}, m.labels...),
+		Notes:    m.notes,
+	}
+}
+
+func newMsgErr(code diag.Code, msg string, line int, col int, endCol int, lines []string, fname string) MsgErr {
 	return MsgErr{
-		msg:     msg,
-		line:    line,
-		col:     col,
-		lineStr: lineStr,
-		fname:   fname,
+		code:   code,
+		msg:    msg,
+		line:   line,
+		col:    col,
+		endCol: endCol,
+		lines:  lines,
+		fname:  fname,
 	}
 }
 
@@ -92,18 +193,35 @@ type UnexpectedTokenErr struct {
 	expected []token.TokenKind
 	line     int
 	col      int
-	lineStr  string
+	endCol   int
+	lines    []string
 	fname    string
+	labels   []diag.Label
 }
 
 func (u UnexpectedTokenErr) Error() string {
+	return u.diagnostic().String()
+}
+
+func (u UnexpectedTokenErr) Diagnostics() []diag.Diagnostic {
+	return []diag.Diagnostic{u.diagnostic()}
+}
+
+func (u UnexpectedTokenErr) diagnostic() diag.Diagnostic {
+	msg := u.message()
+	return diag.Diagnostic{
+		Severity: "error",
+		Code:     diag.UnexpectedToken,
+		Msg:      msg,
+		File:     u.fname,
+		Lines:    u.lines,
+		Labels:   append([]diag.Label{primary(u.line, u.col, u.endCol, msg)}, u.labels...),
+	}
+}
+
+func (u UnexpectedTokenErr) message() string {
 	var err strings.Builder
-	var str strings.Builder
-	r := color.New(color.FgHiRed, color.Bold).FprintfFunc()
-	w := color.New(color.FgHiWhite, color.Bold).FprintfFunc()
-	b := color.New(color.FgHiBlue, color.Bold).FprintfFunc()
 	fmt.Fprint(&err, "Unexpected token expected ")
-
 	for i, expected := range u.expected {
 		switch expected {
 		case token.INT:
@@ -123,7 +241,6 @@ func (u UnexpectedTokenErr) Error() string {
 	}
 	if u.got == nil {
 		fmt.Fprintf(&err, "EOF")
-		u.line++
 	} else {
 		switch u.got.Kind {
 		case token.INT:
@@ -132,55 +249,32 @@ func (u UnexpectedTokenErr) Error() string {
 			fmt.Fprint(&err, u.got.Kind)
 		case token.FLOAT:
 			fmt.Fprint(&err, u.got.Kind)
+		case token.SEMICOLON:
+			if u.got.Literal == "\n" {
+				fmt.Fprint(&err, "newline")
+			} else {
+				fmt.Fprintf(&err, "\"%s\"", u.got.Kind.String())
+			}
 		default:
 			fmt.Fprintf(&err, "\"%s\"", u.got.Kind.String())
 		}
 	}
 
-	r(&str, "error")
-	fmt.Fprint(&str, ": ")
-	w(&str, "%s\n", err.String())
-	b(&str, " --> ")
-	fmt.Fprintf(&str, "%s:%d:", u.fname, u.line)
-	if u.got == nil {
-		fmt.Fprintln(&str, "0")
-	} else {
-		fmt.Fprintln(&str, u.col)
-	}
-	b(&str, "  |\n")
-	b(&str, "%d | ", u.line)
-	if u.got != nil {
-		if u.lineStr[0] == '\n' {
-			fmt.Fprint(&str, u.lineStr[1:])
-			u.col--
-		} else {
-			fmt.Fprint(&str, u.lineStr)
-		}
-	} else {
-		fmt.Fprintln(&str, "")
-	}
-
-	b(&str, "  |")
-	if u.got == nil {
-		fmt.Fprint(&str, " ")
-	} else {
-		for i := 0; i < u.col; i++ {
-			fmt.Fprint(&str, " ")
-		}
-	}
-
-	r(&str, "^ %s\n", err.String())
+	return err.String()
+}
 
-	return str.String()
+func primary(line int, col int, endCol int, msg string) diag.Label {
+	return diag.Label{Span: diag.Span{Line: line, Col: col, EndCol: endCol}, Msg: msg, Primary: true}
 }
 
-func newUnexpectedTokenErr(got *token.Token, expected []token.TokenKind, lineStr string, line int, col int, fname string) UnexpectedTokenErr {
+func newUnexpectedTokenErr(got *token.Token, expected []token.TokenKind, line int, col int, endCol int, lines []string, fname string) UnexpectedTokenErr {
 	return UnexpectedTokenErr{
 		got:      got,
 		expected: expected,
-		lineStr:  lineStr,
 		line:     line,
 		col:      col,
+		endCol:   endCol,
+		lines:    lines,
 		fname:    fname,
 	}
 }
@@ -0,0 +1,73 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"zimlit/graphene/lexer"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"fn add(a: int, b: int): int", true},
+		{"if 1", true},
+		{"while 1\n\tprintln(1)", true},
+		{"println(1,", true},
+		{"(1 +", true},
+		{"let x =", true},
+		{"1 +", true},
+		{"println(\"abc", false},
+		{"let s = \"abc", false},
+		{"fn f(): int\n\tlet s = \"abc", false},
+		{"1 )", false},
+		{"let = 1", false},
+		{"if 1 $", false},
+	}
+	for _, tt := range tests {
+		_, err := ParseSource(context.Background(), "test.gr", tt.src)
+		if got := Incomplete(err); got != tt.want {
+			t.Errorf("Incomplete(%q) = %v, want %v: %v", tt.src, got, tt.want, err)
+		}
+	}
+}
+
+func TestJoinErrorsLimit(t *testing.T) {
+	// every lexical error is reported
+	src := strings.Repeat("let x = $\n", 15)
+	_, err := ParseSource(context.Background(), "test.gr", src)
+	errs, ok := err.(ParseError)
+	if !ok || len(errs) != 15 {
+		t.Fatalf("got %v, want 15 errors", err)
+	}
+	for _, err := range errs {
+		if _, ok := err.(*lexer.LexErr); !ok {
+			t.Errorf("got %v, want a lexical error", err)
+		}
+	}
+
+	// syntax errors past the limit are counted
+	src = "let x = $\n" + strings.Repeat("let = 1\n", 12)
+	_, err = ParseSource(context.Background(), "test.gr", src)
+	errs, ok = err.(ParseError)
+	if !ok || len(errs) != maxErrs+1 {
+		t.Fatalf("got %v, want %d errors", err, maxErrs+1)
+	}
+	if got, want := errs[maxErrs].Error(), "note: 1 more syntax error not shown\n"; got != want {
+		t.Errorf("last error is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"zimlit/graphene/ast/sexpr"
+	"zimlit/graphene/diag"
+	"zimlit/graphene/internal/srctest"
+	"zimlit/graphene/token"
+)
+
+// seed adds the golden test sources to the corpus of f
+func seed(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.gr"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(buf))
+	}
+}
+
+// FuzzParse checks that parsing never panics or hangs, that every syntax
+// error is inside the source and that every tree can be read back from its
+// s-expression
+func FuzzParse(f *testing.F) {
+	seed(f)
+	f.Fuzz(func(t *testing.T, src string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			file, err := ParseSource(context.Background(), "fuzz.gr", src)
+			if err != nil {
+				_ = err.Error()
+			}
+			lines := strings.SplitAfter(src, "\n")
+			if lines[len(lines)-1] == "" {
+				lines = lines[:len(lines)-1]
+			}
+			if errs, ok := err.(ParseError); ok {
+				for _, err := range errs {
+					if _, ok := err.(moreErrs); ok {
+						continue
+					}
+					line, col := errPosition(err)
+					if !srctest.InSource(lines, line, col) {
+						t.Errorf("error at %d:%d is outside of the source: %s", line, col, err)
+					}
+				}
+			}
+
+			for _, stmt := range file.Stmts {
+				read, err := sexpr.Read(stmt.String())
+				if err != nil {
+					t.Errorf("cannot read %s: %v", stmt, err)
+				} else if len(read) != 1 || read[0].String() != stmt.String() {
+					t.Errorf("read %s as %s", stmt, read)
+				}
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Parse did not finish")
+		}
+	})
+}
+
+func FuzzRender(f *testing.F) {
+	f.Add("Expected expression", "let x =\n", 1, 8, false)
+	f.Add("", "", 0, 0, true)
+	f.Add("msg", "\n", 3, -2, true)
+	f.Fuzz(func(t *testing.T, msg string, lineStr string, line int, col int, eof bool) {
+		if col > 1<<16 || line > 1<<10 {
+			t.Skip()
+		}
+		lines := []string{lineStr}
+		if line > 0 {
+			lines = make([]string, line)
+			lines[line-1] = lineStr
+		}
+		_ = newMsgErr(diag.ExpectedExpression, msg, line, col, col+len(msg), lines, "fuzz.gr").Error()
+
+		got := &token.Token{Kind: token.IDENT, Literal: msg, Line: line, Col: col}
+		if eof {
+			got = nil
+		}
+		_ = newUnexpectedTokenErr(got, []token.TokenKind{token.END, token.INT}, line, col, col+len(msg), lines, "fuzz.gr").Error()
+	})
+}
@@ -0,0 +1,99 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package parser
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zimlit/graphene/lexer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGolden lexes and parses every testdata/*.gr file and compares the
+// tokens, the statements and the diagnostics with the .tokens, .ast and
+// .diag files next to it. A missing golden file expects no output
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.gr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/*.gr files")
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".gr"), func(t *testing.T) {
+			buf, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := filepath.Base(file)
+			src := string(buf)
+
+			var tokens strings.Builder
+			l := lexer.NewLexer(src, name)
+			toks, _, _ := l.Lex()
+			for _, tok := range toks {
+				fmt.Fprintf(&tokens, "%d:%d %s %q\n", tok.Line, tok.Col, tok.Kind, tok.Literal)
+			}
+
+			var stmts, diag strings.Builder
+			f, err := ParseSource(context.Background(), name, src)
+			if err != nil {
+				diag.WriteString(err.Error())
+			}
+			for _, stmt := range f.Stmts {
+				fmt.Fprintln(&stmts, stmt.String())
+			}
+
+			base := strings.TrimSuffix(file, ".gr")
+			golden(t, base+".tokens", tokens.String())
+			golden(t, base+".ast", stmts.String())
+			golden(t, base+".diag", diag.String())
+		})
+	}
+}
+
+func golden(t *testing.T, path string, got string) {
+	t.Helper()
+	if *update {
+		var err error
+		if got == "" {
+			err = os.Remove(path)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(path, []byte(got), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs, run go test ./parser -update to accept\n--- want\n%s--- got\n%s", path, want, got)
+	}
+}
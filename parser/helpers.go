@@ -13,7 +13,11 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
 	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
 	"zimlit/graphene/token"
 )
 
@@ -49,6 +53,94 @@ func (p *Parser) check(t token.TokenKind) bool {
 	return p.peek().Kind == t
 }
 
+func (p *Parser) checkNext(t token.TokenKind) bool {
+	if p.pos+1 >= len(p.tokens) {
+		return false
+	}
+
+	return p.tokens[p.pos+1].Kind == t
+}
+
+func (p *Parser) errAt(t *token.Token, code diag.Code, msg string) MsgErr {
+	return newMsgErr(code, msg, t.Line, t.Col, p.end(t), p.lines, p.fname)
+}
+
+// end returns the column just past t in the source. The literal of a
+// string holds its value and an else if holds only its else
+func (p *Parser) end(t *token.Token) int {
+	switch t.Kind {
+	case token.STRING:
+		line := []rune(strings.TrimSuffix(p.lineStr(t.Line), "\n"))
+		for i := t.Col; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 2
+			}
+		}
+		return len(line) + 1
+	case token.ELSEIF:
+		return t.Col + len("else if")
+	}
+	return t.Col + utf8.RuneCountInString(t.Literal)
+}
+
+// unclosed points an error about a missing close token at open, the token
+// that opened the construct it closes. Only the innermost construct that
+// is left open is pointed at
+func (p *Parser) unclosed(err error, open *token.Token, close token.TokenKind) error {
+	u, ok := err.(UnexpectedTokenErr)
+	if !ok || u.labels != nil {
+		return err
+	}
+	for _, kind := range u.expected {
+		if kind == close {
+			u.labels = append(u.labels, diag.Label{
+				Span: diag.Span{Line: open.Line, Col: open.Col, EndCol: p.end(open)},
+				Msg:  "opened here",
+			})
+			return u
+		}
+	}
+	return err
+}
+
+// lineStr returns the source of line, which is empty past the end of the
+// source
+func (p *Parser) lineStr(line int) string {
+	if line < 1 || line > len(p.lines) {
+		return ""
+	}
+	return p.lines[line-1]
+}
+
+// eof returns the position of the end of the source, just past the end of
+// the line of the last token
+func (p *Parser) eof() (line int, col int) {
+	if len(p.tokens) == 0 {
+		return 1, 1
+	}
+	line = p.tokens[len(p.tokens)-1].Line
+	return line, utf8.RuneCountInString(strings.TrimSuffix(p.lineStr(line), "\n")) + 1
+}
+
+// unexpected reports that the current token is not one of types, a token
+// on a later line than the previous one is reported after the previous one
+func (p *Parser) unexpected(types ...token.TokenKind) UnexpectedTokenErr {
+	t := p.peek()
+	if t == nil {
+		line, col := p.eof()
+		return newUnexpectedTokenErr(t, types, line, col, col+1, p.lines, p.fname)
+	}
+	if prev := p.previous(); prev.Line < t.Line {
+		col := p.end(prev)
+		return newUnexpectedTokenErr(t, types, prev.Line, col, col+1, p.lines, p.fname)
+	}
+
+	return newUnexpectedTokenErr(t, types, t.Line, t.Col, p.end(t), p.lines, p.fname)
+}
+
 func (p *Parser) match(types ...token.TokenKind) bool {
 	for _, t := range types {
 		if p.check(t) {
@@ -63,24 +155,25 @@ func (p *Parser) match(types ...token.TokenKind) bool {
 func (p *Parser) consume(types ...token.TokenKind) (bool, error) {
 	if p.match(types...) {
 		return true, nil
-	} else {
-		t := p.peek()
-		if p.previous().Line < t.Line {
-			return false, newUnexpectedTokenErr(t, types, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col+1, p.fname)
-		}
-		if t == nil {
-			return false, newUnexpectedTokenErr(t, types, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
-		}
-
-		return false, newUnexpectedTokenErr(t, types, p.lines[t.Line-1], t.Line, t.Col, p.fname)
 	}
+	return false, p.unexpected(types...)
+}
+
+func posOf(t *token.Token) ast.Pos {
+	return ast.Pos{Line: t.Line, Col: t.Col}
 }
 
 func (p *Parser) synchronize() {
+	if p.match(token.SEMICOLON) {
+		return
+	}
 	p.advance()
 
 	for p.pos < len(p.tokens) {
 		switch p.peek().Kind {
+		case token.SEMICOLON:
+			p.advance()
+			return
 		case token.LET:
 			p.advance()
 			return
@@ -114,20 +207,19 @@ func (p *Parser) kind() (ast.ValueKind, error) {
 			return nil, err
 		}
 		params := []ast.Param{}
-		kind, err := p.kind()
-		if err != nil {
-			_, err = p.consume(token.RPAREN)
-			if err != nil {
-				return nil, err
-			}
-		}
-		params = append(params, ast.NewParam("", kind))
-		for p.match(token.COMMA) {
+		if !p.check(token.RPAREN) {
 			kind, err := p.kind()
 			if err != nil {
 				return nil, err
 			}
 			params = append(params, ast.NewParam("", kind))
+			for p.match(token.COMMA) {
+				kind, err := p.kind()
+				if err != nil {
+					return nil, err
+				}
+				params = append(params, ast.NewParam("", kind))
+			}
 		}
 		_, err = p.consume(token.RPAREN)
 		if err != nil {
@@ -137,15 +229,72 @@ func (p *Parser) kind() (ast.ValueKind, error) {
 		if err != nil {
 			return nil, err
 		}
-		kind, err = p.kind()
+		kind, err := p.kind()
 		if err != nil {
 			return nil, err
 		}
-		return ast.NewFnT(params, kind), nil
+		return ast.NewFnT(nil, params, kind), nil
+	} else if p.match(token.IDENT) {
+		return ast.TypeVar{Name: p.previous().Literal}, nil
 	}
 
-	if p.peek() == nil {
-		return nil, newUnexpectedTokenErr(p.peek(), []token.TokenKind{token.INTK, token.FLOATK, token.STRINGK, token.FN}, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
+	return nil, p.unexpected(token.INTK, token.FLOATK, token.STRINGK, token.FN, token.IDENT)
+}
+
+func (p *Parser) kinds(end token.TokenKind) ([]ast.ValueKind, error) {
+	kinds := []ast.ValueKind{}
+	for {
+		kind, err := p.kind()
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+		if !p.match(token.COMMA) {
+			break
+		}
 	}
-	return nil, newUnexpectedTokenErr(p.peek(), []token.TokenKind{token.INTK, token.FLOATK, token.STRINGK, token.FN}, p.lines[p.peek().Line-1], p.peek().Line, p.peek().Col, p.fname)
+	_, err := p.consume(end)
+	if err != nil {
+		return nil, err
+	}
+
+	return kinds, nil
+}
+
+func (p *Parser) typeParams() ([]ast.TypeParam, error) {
+	params := []ast.TypeParam{}
+	for {
+		_, err := p.consume(token.IDENT)
+		if err != nil {
+			return nil, err
+		}
+		param := ast.NewTypeParam(p.previous().Literal, ast.ANY)
+		if p.match(token.COLON) {
+			_, err := p.consume(token.IDENT)
+			if err != nil {
+				return nil, err
+			}
+			c := p.previous()
+			switch c.Literal {
+			case "any":
+				param.Constraint = ast.ANY
+			case "comparable":
+				param.Constraint = ast.COMPARABLE
+			case "numeric":
+				param.Constraint = ast.NUMERIC
+			default:
+				return nil, p.errAt(c, diag.UnknownConstraint, fmt.Sprintf("Unknown constraint %s, expected any, comparable or numeric", c.Literal))
+			}
+		}
+		params = append(params, param)
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+	_, err := p.consume(token.RBRACKET)
+	if err != nil {
+		return nil, err
+	}
+
+	return params, nil
 }
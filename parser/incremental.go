@@ -0,0 +1,140 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package parser
+
+import (
+	"context"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/lexer"
+	"zimlit/graphene/token"
+)
+
+// Tree is a parsed file along with the tokens it was parsed from, which
+// lets Reparse reuse the statements an edit does not touch
+type Tree struct {
+	File   ast.File
+	tokens []token.Token
+	// starts holds the index of the first token of each statement
+	starts []int
+	// reused is the number of statements taken from the previous tree
+	reused int
+}
+
+// ParseTree lexes and parses src like ParseSource and keeps what is needed
+// to reparse it after an edit
+func ParseTree(ctx context.Context, name string, src string) (*Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l := lexer.NewLexer(src, name)
+	toks, lines, lexErrs := l.Lex()
+	return parseTree(ctx, toks, lines, name, lexErrs)
+}
+
+func parseTree(ctx context.Context, toks []token.Token, lines []string, name string, lexErrs lexer.LexErrs) (*Tree, error) {
+	p := NewParser(toks, lines, name)
+	p.ctx = ctx
+	stmts, starts, err := p.statements(nil)
+	if err = JoinErrors(lexErrs, err); err != nil {
+		return nil, err
+	}
+	return &Tree{
+		File:   ast.File{Name: name, Lines: lines, Stmts: stmts},
+		tokens: toks,
+		starts: starts,
+	}, nil
+}
+
+// Reparse returns the tree of the source made by applying e to the source
+// of t, which is equal to the tree a full parse of that source returns.
+// Only the lines the edit touches are lexed again, and only the statements
+// from the first one reaching those lines up to the first one after them
+// that starts on a statement boundary are parsed again
+func (t *Tree) Reparse(ctx context.Context, e lexer.Edit) (*Tree, error) {
+	name := t.File.Name
+	toks, lines, lexErrs, err := lexer.Relex(t.tokens, t.File.Lines, e, name)
+	if err != nil {
+		return nil, err
+	}
+	if lexErrs != nil {
+		return parseTree(ctx, toks, lines, name, lexErrs)
+	}
+	_, first, last, n, _ := e.Apply(t.File.Lines)
+	lineDelta := n - (last - first + 1)
+	tokDelta := len(toks) - len(t.tokens)
+
+	// the statements that end with a semicolon before the edited lines
+	// parse the same, their tokens are unchanged
+	prefix := 0
+	for prefix < len(t.starts) {
+		end := len(t.tokens)
+		if prefix+1 < len(t.starts) {
+			end = t.starts[prefix+1]
+		}
+		if t.tokens[end-1].Line >= first || t.tokens[end-1].Kind != token.SEMICOLON {
+			break
+		}
+		prefix++
+	}
+	start := 0
+	if prefix > 0 && prefix == len(t.starts) {
+		start = len(t.tokens)
+	} else if prefix > 0 {
+		start = t.starts[prefix]
+	}
+
+	// a statement after the edited lines parses the same once the new
+	// statements reach its first token
+	suffix := len(t.starts)
+	reuse := func(pos int) bool {
+		for k := prefix; k < len(t.starts); k++ {
+			old := t.starts[k]
+			if t.tokens[old].Line > last && old+tokDelta == pos {
+				suffix = k
+				return true
+			}
+		}
+		return false
+	}
+
+	p := NewParser(toks, lines, name)
+	p.ctx = ctx
+	p.pos = start
+	stmts, starts, err := p.statements(reuse)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &Tree{
+		File:   ast.File{Name: name, Lines: lines},
+		tokens: toks,
+		reused: prefix + len(t.starts) - suffix,
+	}
+	tree.File.Stmts = append(tree.File.Stmts, t.File.Stmts[:prefix]...)
+	tree.File.Stmts = append(tree.File.Stmts, stmts...)
+	tree.starts = append(tree.starts, t.starts[:prefix]...)
+	tree.starts = append(tree.starts, starts...)
+	for k := suffix; k < len(t.starts); k++ {
+		stmt := t.File.Stmts[k]
+		if lineDelta != 0 {
+			stmt = ast.ShiftLines(stmt, lineDelta)
+		}
+		tree.File.Stmts = append(tree.File.Stmts, stmt)
+		tree.starts = append(tree.starts, t.starts[k]+tokDelta)
+	}
+	if tree.File.Stmts == nil {
+		tree.File.Stmts = ast.Stmts{}
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,122 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package parser
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/internal/srctest"
+	"zimlit/graphene/lexer"
+)
+
+const program = `fn one(): int
+  return 1
+end
+let two = one() + 1
+fn three(a: int): int
+  if a > 2
+    return a
+  end
+  return 3
+end
+`
+
+// randomEdit returns a random edit of src and the source it produces
+func randomEdit(r *rand.Rand, src string) (lexer.Edit, string) {
+	e, edited := srctest.RandomEdit(r, src)
+	return lexer.Edit(e), edited
+}
+
+func marshal(t *testing.T, f ast.File) string {
+	buf, err := ast.MarshalJSON(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+// TestReparse checks that reparsing chains of random edits gives the tree
+// or the errors of parsing the edited source from scratch
+func TestReparse(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.gr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := []string{program, "", "x"}
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, string(buf))
+	}
+
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(1))
+	for _, src := range srcs {
+		tree, err := ParseTree(ctx, "test.gr", src)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < 300; i++ {
+			e, edited := randomEdit(r, src)
+			want, wantErr := ParseTree(ctx, "test.gr", edited)
+			got, gotErr := tree.Reparse(ctx, e)
+
+			if (gotErr == nil) != (wantErr == nil) || (gotErr != nil && gotErr.Error() != wantErr.Error()) {
+				t.Fatalf("reparsing %q after %+v gave error\n%v\nwant\n%v", src, e, gotErr, wantErr)
+			}
+			if wantErr != nil {
+				continue
+			}
+			if g, w := marshal(t, got.File), marshal(t, want.File); g != w {
+				t.Fatalf("reparsing %q after %+v gave\n%s\nwant\n%s", src, e, g, w)
+			}
+			src, tree = edited, got
+		}
+	}
+}
+
+func TestReparseReuses(t *testing.T) {
+	ctx := context.Background()
+	tree, err := ParseTree(ctx, "test.gr", program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		edit   lexer.Edit
+		reused int
+	}{
+		{"in a line", lexer.Edit{StartLine: 4, StartCol: 19, EndLine: 4, EndCol: 20, Text: "2"}, 2},
+		{"adding lines", lexer.Edit{StartLine: 2, StartCol: 1, EndLine: 2, EndCol: 1, Text: "let z = 0\n"}, 2},
+		{"removing lines", lexer.Edit{StartLine: 2, StartCol: 1, EndLine: 3, EndCol: 1}, 2},
+		{"joining statements", lexer.Edit{StartLine: 3, StartCol: 4, EndLine: 4, EndCol: 1, Text: "; "}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tree.Reparse(ctx, tt.edit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.reused != tt.reused {
+				t.Errorf("reused %d statements, want %d", got.reused, tt.reused)
+			}
+		})
+	}
+}
@@ -13,16 +13,24 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
 	"zimlit/graphene/token"
 )
 
+// maxErrs is the number of errors reported before giving up
+const maxErrs = 10
+
 type Parser struct {
 	tokens []token.Token
 	pos    int
 	lines  []string
 	fname  string
+	// number of fn bodies enclosing the current position
+	fnDepth int
+	ctx     context.Context
 }
 
 func NewParser(tokens []token.Token, lines []string, fname string) Parser {
@@ -31,116 +39,237 @@ func NewParser(tokens []token.Token, lines []string, fname string) Parser {
 		pos:    0,
 		lines:  lines,
 		fname:  fname,
+		ctx:    context.Background(),
+	}
+}
+
+// Parse parses every token, returning all of the syntax errors found
+func (p *Parser) Parse() (ast.File, error) {
+	stmts, _, err := p.statements(nil)
+	if err != nil {
+		return ast.File{}, err
+	}
+
+	return ast.File{Name: p.fname, Lines: p.lines, Stmts: stmts}, nil
+}
+
+// ParseStarts parses like Parse and also returns the index of the first
+// token of each statement
+func (p *Parser) ParseStarts() (ast.File, []int, error) {
+	stmts, starts, err := p.statements(nil)
+	if err != nil {
+		return ast.File{}, nil, err
 	}
+
+	return ast.File{Name: p.fname, Lines: p.lines, Stmts: stmts}, starts, nil
 }
 
-func (p *Parser) Parse(c chan ParseResult) {
-	exprs := []ast.Expr{}
+// statements parses top level statements until the tokens run out or, while
+// there are no errors, reuse reports that the statement starting at the
+// current token does not need to be parsed. It returns the index of the
+// first token of each statement along with the statements
+func (p *Parser) statements(reuse func(pos int) bool) (ast.Stmts, []int, error) {
+	stmts := []ast.Stmt{}
+	starts := []int{}
 	var errs ParseError = nil
 
 	for p.pos < len(p.tokens) {
-		expr, err := p.expression()
+		if err := p.ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if p.match(token.SEMICOLON) {
+			continue
+		}
+		if errs == nil && reuse != nil && reuse(p.pos) {
+			break
+		}
+		starts = append(starts, p.pos)
+		stmt, err := p.declaration()
+		if err == nil {
+			err = p.terminator()
+		}
 		if err != nil {
 			errs = append(errs, err)
+			if len(errs) == maxErrs {
+				break
+			}
 			p.synchronize()
 		}
-		exprs = append(exprs, expr)
+		stmts = append(stmts, stmt)
 	}
 
 	if errs != nil {
-		c <- ParseResult{nil, errs}
+		return nil, nil, errs
 	}
-
-	c <- ParseResult{exprs, nil}
+	return stmts, starts, nil
 }
 
 func (p *Parser) expression() (ast.Expr, error) {
-	return p.returnExpr()
+	return p.binary(0)
 }
 
-func (p *Parser) unary() (ast.Expr, error) {
-	if p.match(token.MINUS, token.BANG) {
-		operator := p.previous()
-		right, err := p.unary()
+func (p *Parser) primary() (ast.Expr, error) {
+	if p.match(token.INT, token.FLOAT, token.NIL, token.IDENT) {
+		return ast.NewLiteral(p.previous().Literal, p.previous().Kind, posOf(p.previous())), nil
+	}
+	// the lexer has reported the illegal token, it stands in for an operand
+	// so that the rest of the source is still parsed
+	if p.match(token.ILLEGAL) {
+		return ast.NewLiteral(p.previous().Literal, token.ILLEGAL, posOf(p.previous())), nil
+	}
+	if p.match(token.STRING) {
+		return ast.NewLiteral(fmt.Sprintf("\"%s\"", p.previous().Literal), p.previous().Kind, posOf(p.previous())), nil
+	}
+	// a type keyword followed by ( names the conversion builtin of that type
+	if (p.check(token.INTK) || p.check(token.FLOATK) || p.check(token.STRINGK)) && p.checkNext(token.LPAREN) {
+		p.advance()
+		return ast.NewLiteral(p.previous().Kind.String(), token.IDENT, posOf(p.previous())), nil
+	}
+
+	if p.match(token.LPAREN) {
+		open := p.previous()
+		expr, err := p.expression()
 		if err != nil {
-			return nil, err
+			return nil, p.unclosed(err, open, token.RPAREN)
+		}
+		c, err := p.consume(token.RPAREN)
+		if !c {
+			return nil, p.unclosed(err, open, token.RPAREN)
+		}
+		return ast.NewGrouping(expr), nil
+	}
+
+	if p.match(token.IF) {
+		open := p.previous()
+		expr, err := p.ifExpr()
+		return expr, p.unclosed(err, open, token.END)
+	}
+	if p.match(token.FN) {
+		if p.check(token.IDENT) {
+			return nil, p.errAt(p.previous(), diag.NamedFnAsValue, "A named fn is a declaration and cannot be used as a value, remove the name to make it anonymous")
 		}
-		return ast.NewUnary(*operator, right), nil
+		return p.function(p.previous())
+	}
+	if p.match(token.LET, token.WHILE, token.RETURN, token.IMPORT, token.PUB) {
+		t := p.previous()
+		return nil, p.errAt(t, diag.StatementAsValue, fmt.Sprintf("\"%s\" starts a statement and cannot be used as a value", t.Kind))
 	}
 
-	return p.call()
+	if p.peek() == nil {
+		line, col := p.eof()
+		err := newMsgErr(diag.ExpectedExpression, "Expected expression", line, col, col+1, p.lines, p.fname)
+		err.eof = true
+		return nil, err
+	}
+	return nil, p.errAt(p.peek(), diag.ExpectedExpression, "Expected expression")
 }
 
-func (p *Parser) call() (ast.Expr, error) {
-	expr, err := p.primary()
+func (p *Parser) ifExpr() (ast.Expr, error) {
+	pos := posOf(p.previous())
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.block(token.ELSE, token.ELSEIF, token.END)
 	if err != nil {
 		return nil, err
 	}
 
-	for {
-		if p.match(token.LPAREN) {
-			expr, err = p.finishCall(expr)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			break
+	var else_ifs []ast.IfExpr
+	for p.match(token.ELSEIF) {
+		epos := posOf(p.previous())
+		econd, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		ebody, err := p.block(token.ELSE, token.ELSEIF, token.END)
+		if err != nil {
+			return nil, err
+		}
+		else_ifs = append(else_ifs, ast.NewIfExpr(econd, ebody, nil, nil, epos))
+	}
+
+	var el []ast.Stmt
+	if p.match(token.ELSE) {
+		el, err = p.block(token.END)
+		if err != nil {
+			return nil, err
+		}
+		if el == nil {
+			el = []ast.Stmt{}
 		}
 	}
+	c, err := p.consume(token.END)
+	if !c {
+		return nil, err
+	}
 
-	return expr, nil
+	i := ast.NewIfExpr(cond, body, else_ifs, el, pos)
+	i.End = posOf(p.previous())
+	return i, nil
 }
 
-func (p *Parser) finishCall(callee ast.Expr) (ast.Expr, error) {
-	args := []ast.Expr{}
+// function parses the part of a fn after the fn keyword and its name
+func (p *Parser) function(fn *token.Token) (ast.FnExpr, error) {
+	var typeParams []ast.TypeParam
+	if p.match(token.LBRACKET) {
+		var err error
+		typeParams, err = p.typeParams()
+		if err != nil {
+			return ast.FnExpr{}, err
+		}
+	}
+	_, err := p.consume(token.LPAREN)
+	if err != nil {
+		return ast.FnExpr{}, err
+	}
+	params := []ast.Param{}
 	if !p.check(token.RPAREN) {
 		for {
-			x, err := p.expression()
+			_, err := p.consume(token.IDENT)
+			if err != nil {
+				return ast.FnExpr{}, err
+			}
+			name := p.previous().Literal
+			_, err = p.consume(token.COLON)
 			if err != nil {
-				return nil, err
+				return ast.FnExpr{}, err
 			}
-			args = append(args, x)
+			kind, err := p.kind()
+			if err != nil {
+				return ast.FnExpr{}, err
+			}
+			params = append(params, ast.NewParam(name, kind))
 			if !p.match(token.COMMA) {
 				break
 			}
 		}
 	}
-
-	_, err := p.consume(token.RPAREN)
+	_, err = p.consume(token.RPAREN)
 	if err != nil {
-		return nil, err
+		return ast.FnExpr{}, err
 	}
-
-	return ast.NewCall(callee, args), nil
-}
-
-func (p *Parser) primary() (ast.Expr, error) {
-	if p.match(token.INT, token.FLOAT, token.NIL, token.IDENT) {
-		return ast.NewLiteral(p.previous().Literal, p.previous().Kind), nil
+	_, err = p.consume(token.COLON)
+	if err != nil {
+		return ast.FnExpr{}, err
 	}
-	if p.match(token.STRING) {
-		return ast.NewLiteral(fmt.Sprintf("\"%s\"", p.previous().Literal), p.previous().Kind), nil
+	kind, err := p.kind()
+	if err != nil {
+		return ast.FnExpr{}, err
 	}
 
-	if p.match(token.LPAREN) {
-		expr, err := p.expression()
-		if err != nil {
-			return nil, err
-		}
-		c, err := p.consume(token.RPAREN)
-		if !c {
-			return nil, err
-		}
-		return ast.NewGrouping(expr), nil
+	p.fnDepth++
+	body, err := p.block(token.END)
+	p.fnDepth--
+	if err != nil {
+		return ast.FnExpr{}, p.unclosed(err, fn, token.END)
 	}
-
-	if p.peek() == nil {
-		if p.previous() == nil {
-			return nil, newMsgErr("Expected expression", 1, 1, "", p.fname)
-		}
-		return nil, newMsgErr("Expected expression", p.previous().Line, p.previous().Col+1, p.lines[p.previous().Line-1], p.fname)
-	} else {
-		return nil, newMsgErr("Expected expression", p.peek().Line, p.peek().Col, p.lines[p.peek().Line-1], p.fname)
+	c, err := p.consume(token.END)
+	if !c {
+		return ast.FnExpr{}, p.unclosed(err, fn, token.END)
 	}
 
+	f := ast.NewFn(typeParams, params, body, kind, posOf(fn))
+	f.End = posOf(p.previous())
+	return f, nil
 }
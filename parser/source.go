@@ -0,0 +1,48 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package parser
+
+import (
+	"context"
+	"os"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/lexer"
+)
+
+// ParseSource lexes and parses src, name is the file name used in error
+// messages. Parsing stops with the error of ctx once it is done
+func ParseSource(ctx context.Context, name string, src string) (ast.File, error) {
+	if err := ctx.Err(); err != nil {
+		return ast.File{}, err
+	}
+	l := lexer.NewLexer(src, name)
+	toks, lines, lexErrs := l.Lex()
+
+	p := NewParser(toks, lines, name)
+	p.ctx = ctx
+	f, err := p.Parse()
+	if err = JoinErrors(lexErrs, err); err != nil {
+		return ast.File{}, err
+	}
+	return f, nil
+}
+
+// ParseFile reads and parses the file at path
+func ParseFile(ctx context.Context, path string) (ast.File, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return ast.File{}, err
+	}
+
+	return ParseSource(ctx, path, string(buf))
+}
@@ -14,239 +14,203 @@ package parser
 
 import (
 	"zimlit/graphene/ast"
+	"zimlit/graphene/diag"
 	"zimlit/graphene/token"
 )
 
-func (p *Parser) returnExpr() (ast.Expr, error) {
-	if p.match(token.RETURN) {
-		value, err := p.expression()
-		if err != nil {
-			return nil, err
-		}
-		return ast.NewReturn(value), nil
+// declaration parses a top level statement, the only place imports and pub
+// declarations are allowed
+func (p *Parser) declaration() (ast.Stmt, error) {
+	if p.match(token.IMPORT) {
+		return p.importDecl()
+	}
+	if p.match(token.PUB) {
+		return p.pubDecl()
 	}
 
-	return p.whileExpr()
+	return p.statement()
 }
 
-func (p *Parser) whileExpr() (ast.Expr, error) {
+func (p *Parser) statement() (ast.Stmt, error) {
+	if p.match(token.RETURN) {
+		return p.returnStmt()
+	}
 	if p.match(token.WHILE) {
-		cond, err := p.expression()
-		if err != nil {
-			return nil, err
-		}
-
-		var body []ast.Expr
-		for {
-			b, err := p.expression()
-			if p.peek() == nil {
-				return nil, newUnexpectedTokenErr(nil, []token.TokenKind{token.END}, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
-			}
-			if err != nil {
-				if p.peek().Kind == token.END {
-					break
-				}
+		return p.whileStmt()
+	}
+	if p.match(token.LET) {
+		return p.varDecl()
+	}
+	if p.check(token.FN) && p.checkNext(token.IDENT) {
+		p.advance()
+		return p.fnDecl()
+	}
+	if p.match(token.IMPORT) {
+		return nil, p.errAt(p.previous(), diag.NotTopLevel, "Imports are only allowed at the top level")
+	}
+	if p.match(token.PUB) {
+		return nil, p.errAt(p.previous(), diag.NotTopLevel, "Pub declarations are only allowed at the top level")
+	}
 
-				return nil, err
-			}
-			body = append(body, b)
-		}
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewExprStmt(expr), nil
+}
 
-		c, err := p.consume(token.END)
-		if !c {
-			return nil, err
+// terminator consumes the ";" or newline after a statement, which may be
+// left out before one of the tokens in end or EOF
+func (p *Parser) terminator(end ...token.TokenKind) error {
+	if p.match(token.SEMICOLON) || p.peek() == nil {
+		return nil
+	}
+	for _, kind := range end {
+		if p.check(kind) {
+			return nil
 		}
-
-		return ast.NewWhileExpr(cond, body), nil
 	}
 
-	return p.ifExpr()
+	return p.errAt(p.peek(), diag.MissingSeparator, "Statements on the same line must be separated by \";\"")
 }
 
-func (p *Parser) ifExpr() (ast.Expr, error) {
-	if p.match(token.IF) {
-		cond, err := p.expression()
-		if err != nil {
+// block parses statements up to one of the tokens in end, which is left for
+// the caller to consume. Empty statements, such as the ";" after an if or
+// while condition, are skipped
+func (p *Parser) block(end ...token.TokenKind) ([]ast.Stmt, error) {
+	var body []ast.Stmt
+	for {
+		if err := p.ctx.Err(); err != nil {
 			return nil, err
 		}
-		var body []ast.Expr
-		for {
-			b, err := p.expression()
-			if err != nil {
-				if p.peek() == nil {
-					return nil, newUnexpectedTokenErr(nil, []token.TokenKind{token.END}, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
-				}
-				if p.peek().Kind == token.ELSE || p.peek().Kind == token.ELSEIF || p.peek().Kind == token.END {
-					break
-				}
-
-				return nil, err
-			}
-			body = append(body, b)
+		if p.match(token.SEMICOLON) {
+			continue
 		}
-
-		var else_ifs []ast.IfExpr
-		for p.match(token.ELSEIF) {
-			econd, err := p.expression()
-			if err != nil {
-				return nil, err
-			}
-
-			var ebody []ast.Expr
-			for {
-				e, err := p.expression()
-				if p.peek() == nil {
-					return nil, newUnexpectedTokenErr(nil, []token.TokenKind{token.END}, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
-				}
-				if err != nil {
-					if p.peek().Kind == token.ELSE || p.peek().Kind == token.ELSEIF || p.peek().Kind == token.END {
-						break
-					}
-					return nil, err
-				}
-				ebody = append(ebody, e)
-			}
-
-			else_if := ast.NewIfExpr(econd, ebody, nil, nil)
-
-			else_ifs = append(else_ifs, else_if)
+		if p.peek() == nil {
+			return nil, p.unexpected(token.END)
 		}
-
-		var el []ast.Expr
-		if p.match(token.ELSE) {
-			for {
-				e, err := p.expression()
-				if p.peek() == nil {
-					return nil, newUnexpectedTokenErr(nil, []token.TokenKind{token.END}, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
-				}
-				if err != nil {
-					if p.peek().Kind == token.ELSE || p.peek().Kind == token.ELSEIF || p.peek().Kind == token.END {
-						break
-					}
-					return nil, err
-				}
-				el = append(el, e)
+		for _, kind := range end {
+			if p.check(kind) {
+				return body, nil
 			}
 		}
-		c, err := p.consume(token.END)
-		if !c {
+		stmt, err := p.statement()
+		if err != nil {
 			return nil, err
 		}
+		err = p.terminator(end...)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, stmt)
+	}
+}
 
-		return ast.NewIfExpr(cond, body, else_ifs, el), nil
+func (p *Parser) returnStmt() (ast.Stmt, error) {
+	ret := p.previous()
+	if p.fnDepth == 0 {
+		return nil, p.errAt(ret, diag.ReturnOutsideFn, "Return is only allowed inside a fn")
+	}
+	value, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewReturn(value, posOf(ret)), nil
+}
 
+func (p *Parser) whileStmt() (ast.Stmt, error) {
+	open := p.previous()
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.block(token.END)
+	if err != nil {
+		return nil, p.unclosed(err, open, token.END)
+	}
+	c, err := p.consume(token.END)
+	if !c {
+		return nil, p.unclosed(err, open, token.END)
 	}
 
-	return p.varDecl()
+	return ast.NewWhileStmt(cond, body, posOf(open)), nil
 }
 
-func (p *Parser) varDecl() (ast.Expr, error) {
-	if p.match(token.LET) {
-		is_mut := p.match(token.MUT)
-		c, err := p.consume(token.IDENT)
-		if !c {
-			return nil, err
-		}
-		name := p.previous()
-		c, err = p.consume(token.COLON)
-		if !c {
-			return nil, err
-		}
-		kind, err := p.kind()
-		if err != nil {
-			return nil, err
-		}
-		var value ast.Expr = ast.NewLiteral("nil", token.NIL)
-		if p.match(token.EQ) {
-			value, err = p.expression()
-			if err != nil {
-				return nil, err
-			}
-		}
-		return ast.NewVarDecl(name.Literal, kind, value, is_mut), nil
+func (p *Parser) importDecl() (ast.Stmt, error) {
+	pos := posOf(p.previous())
+	_, err := p.consume(token.STRING)
+	if err != nil {
+		return nil, err
 	}
 
-	return p.fn()
+	return ast.NewImport(p.previous().Literal, pos), nil
 }
 
-func (p *Parser) fn() (ast.Expr, error) {
-	if p.match(token.FN) {
-		name := ""
-		if p.match(token.IDENT) {
-			name = p.previous().Literal
-		}
-		_, err := p.consume(token.LPAREN)
+func (p *Parser) pubDecl() (ast.Stmt, error) {
+	pub := p.previous()
+	if p.match(token.LET) {
+		decl, err := p.varDecl()
 		if err != nil {
 			return nil, err
 		}
-		params := []ast.Param{}
-		if p.match(token.IDENT) {
-			name := p.previous().Literal
-			_, err := p.consume(token.COLON)
-			if err != nil {
-				return nil, err
-			}
-			kind, err := p.kind()
-			if err != nil {
-				return nil, err
-			}
-			params = append(params, ast.NewParam(name, kind))
-			for p.match(token.COMMA) {
-				_, err := p.consume(token.IDENT)
-				if err != nil {
-					return nil, err
-				}
-				name = p.previous().Literal
-				_, err = p.consume(token.COLON)
-				if err != nil {
-					return nil, err
-				}
-				kind, err := p.kind()
-				if err != nil {
-					return nil, err
-				}
-				params = append(params, ast.NewParam(name, kind))
-			}
+		return decl.(ast.VarDecl).Export(), nil
+	}
+	if p.check(token.FN) {
+		if !p.checkNext(token.IDENT) {
+			return nil, p.errAt(pub, diag.PubNotNamed, "Only named fns can be pub")
 		}
-		_, err = p.consume(token.RPAREN)
+		p.advance()
+		decl, err := p.fnDecl()
 		if err != nil {
 			return nil, err
 		}
-		_, err = p.consume(token.COLON)
+		return decl.(ast.VarDecl).Export(), nil
+	}
+
+	_, err := p.consume(token.LET, token.FN)
+	return nil, err
+}
+
+func (p *Parser) varDecl() (ast.Stmt, error) {
+	is_mut := p.match(token.MUT)
+	c, err := p.consume(token.IDENT)
+	if !c {
+		return nil, err
+	}
+	name := p.previous()
+	var kind ast.ValueKind
+	if p.match(token.COLON) {
+		kind, err = p.kind()
 		if err != nil {
 			return nil, err
 		}
-		kind, err := p.kind()
+	} else if !p.check(token.EQ) {
+		_, err = p.consume(token.COLON, token.EQ)
+		return nil, err
+	}
+	var value ast.Expr = ast.NewLiteral("nil", token.NIL, posOf(p.previous()))
+	if p.match(token.EQ) {
+		value, err = p.expression()
 		if err != nil {
 			return nil, err
 		}
+	}
+	return ast.NewVarDecl(name.Literal, kind, value, is_mut, posOf(name)), nil
+}
 
-		var body []ast.Expr
-		for {
-			b, err := p.expression()
-			if p.peek() == nil {
-				return nil, newUnexpectedTokenErr(nil, []token.TokenKind{token.END}, p.lines[p.previous().Line-1], p.previous().Line, p.previous().Col, p.fname)
-			}
-			if err != nil {
-				if p.peek().Kind == token.END {
-					break
-				}
-
-				return nil, err
-			}
-			body = append(body, b)
-		}
-
-		c, err := p.consume(token.END)
-		if !c {
-			return nil, err
-		}
-		f := ast.NewFn(params, body, kind)
-		if name != "" {
-			return ast.NewVarDecl(name, ast.NewFnT(params, kind), f, false), nil
-		}
-		return f, nil
+// fnDecl parses fn name(...) as a binding of name to an anonymous fn
+func (p *Parser) fnDecl() (ast.Stmt, error) {
+	fn := p.previous()
+	p.advance()
+	name := p.previous()
+	f, err := p.function(fn)
+	if err != nil {
+		return nil, err
 	}
 
-	return p.assignment()
+	params := []ast.Param{}
+	for _, param := range f.Params {
+		params = append(params, ast.NewParam("", param.Kind))
+	}
+	return ast.NewVarDecl(name.Literal, ast.NewFnT(f.TypeParams, params, f.Rtype), f, false, posOf(name)), nil
 }
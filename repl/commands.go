@@ -0,0 +1,145 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrQuit is returned by the :quit command
+var ErrQuit = errors.New("quit")
+
+type command struct {
+	name string
+	arg  string
+	help string
+	run  func(s *Session, ctx context.Context, arg string, w io.Writer) error
+}
+
+// commands are the meta commands of a session, written :name arg. It is set
+// in init as :help refers to it
+var commands []command
+
+func init() {
+	commands = []command{
+		{"type", "<expr>", "show the inferred type of <expr>", typeCmd},
+		{"ast", "<expr>", "show the syntax tree of <expr>", astCmd},
+		{"tokens", "<src>", "show the tokens of <src>", tokensCmd},
+		{"load", "<file>", "evaluate <file> in the session", loadCmd},
+		{"reset", "", "discard every binding", resetCmd},
+		{"env", "", "list the bindings and their types", envCmd},
+		{"help", "", "list the commands", helpCmd},
+		{"quit", "", "leave the session", quitCmd},
+	}
+}
+
+// IsCommand reports whether line is a meta command rather than source
+func IsCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+// Command runs the meta command line, writing its output to w
+func (s *Session) Command(ctx context.Context, line string, w io.Writer) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line)[1:], " ")
+	arg = strings.TrimSpace(arg)
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if c.arg != "" && arg == "" {
+			return fmt.Errorf("usage: :%s %s", c.name, c.arg)
+		}
+		return c.run(s, ctx, arg, w)
+	}
+	return fmt.Errorf("unknown command :%s, :help lists the commands", name)
+}
+
+func typeCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	kind, err := s.Type(ctx, arg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, kind)
+	return nil
+}
+
+func astCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	stmts, err := s.AST(ctx, arg)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		fmt.Fprintln(w, stmt)
+	}
+	return nil
+}
+
+func tokensCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	toks, err := s.Tokens(arg)
+	if err != nil {
+		return err
+	}
+	for _, tok := range toks {
+		fmt.Fprintf(w, "%d:%d %s %q\n", tok.Line, tok.Col, tok.Kind, tok.Literal)
+	}
+	return nil
+}
+
+func loadCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	result, err := s.Load(ctx, arg)
+	if err != nil {
+		return err
+	}
+	if result.Show {
+		fmt.Fprintln(w, result)
+	}
+	return nil
+}
+
+func resetCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	s.Reset()
+	return nil
+}
+
+func envCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	for _, b := range s.Bindings() {
+		if b.Builtin {
+			continue
+		}
+		mut := ""
+		if b.Mut {
+			mut = "mut "
+		}
+		kind := "module"
+		if b.Kind != nil {
+			kind = b.Kind.String()
+		}
+		fmt.Fprintf(w, "%s%s : %s\n", mut, b.Name, kind)
+	}
+	return nil
+}
+
+func helpCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", strings.TrimSpace(":"+c.name+" "+c.arg), c.help)
+	}
+	return nil
+}
+
+func quitCmd(s *Session, ctx context.Context, arg string, w io.Writer) error {
+	return ErrQuit
+}
@@ -0,0 +1,114 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package repl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"zimlit/graphene/repl"
+)
+
+func TestCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lib.gr")
+	if err := os.WriteFile(file, []byte("let loaded = 7\nloaded * 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string
+		want string
+		// err is a part of the error, empty if there is none
+		err string
+	}{
+		{line: ":type cube", want: "(fn int (int))\n"},
+		{line: ":type count + 1.5", err: "invalid operation + on int and float"},
+		{line: ":type let q = 1", want: "nil\n"},
+		{line: ":type q", err: "undefined"},
+		{line: ":type", err: "usage: :type <expr>"},
+		{line: "  :ast 1 + 2 * x  ", want: "(+ 1 (* 2 x))\n"},
+		{line: ":ast (", err: "error"},
+		{line: ":tokens let x", want: "1:1 let \"let\"\n1:5 identifier \"x\"\n"},
+		{line: ":tokens $", err: "Unexpected character '$'"},
+		{line: ":env", want: "mut count : int\ncube : (fn int (int))\n"},
+		{line: ":load " + file, want: "=> 14 : int\n"},
+		{line: ":load " + file + ".missing", err: "no such file"},
+		{line: ":type loaded", want: "int\n"},
+		{line: ":reset"},
+		{line: ":env"},
+		{line: ":type cube", err: "undefined"},
+		{line: ":quit", err: repl.ErrQuit.Error()},
+		{line: ":nope", err: "unknown command :nope"},
+	}
+	s := repl.NewSession("stdin")
+	ctx := context.Background()
+	if _, err := s.Eval(ctx, "let mut count = 1\nfn cube(x: int): int x * x * x end"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if !repl.IsCommand(tt.line) {
+			t.Errorf("%q is not a command", tt.line)
+		}
+		var out strings.Builder
+		err := s.Command(ctx, tt.line, &out)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%q gave error %v, want %q", tt.line, err, tt.err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%q wrote %q, want %q", tt.line, out.String(), tt.want)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	var out strings.Builder
+	if err := repl.NewSession("stdin").Command(context.Background(), ":help", &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range []string{":type <expr>", ":ast <expr>", ":tokens <src>", ":load <file>", ":reset", ":env", ":help", ":quit"} {
+		if !strings.Contains(out.String(), "  "+cmd+" ") {
+			t.Errorf(":help does not list %s:\n%s", cmd, out.String())
+		}
+	}
+	if repl.IsCommand("x :y") {
+		t.Error("x :y is a command")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := repl.NewSession("stdin")
+	if _, err := s.Eval(context.Background(), "let mut count = 1\nfn cube(x: int): int x * x * x end"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"c", []string{"concat", "count", "cube"}},
+		{"cu", []string{"cube"}},
+		{"e", []string{"else", "end", "exit"}},
+		// int is a keyword and a builtin
+		{"in", []string{"int"}},
+		{":", []string{":ast", ":env", ":help", ":load", ":quit", ":reset", ":tokens", ":type"}},
+		{":t", []string{":tokens", ":type"}},
+		{"zz", nil},
+	}
+	for _, tt := range tests {
+		if got := s.Complete(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package repl implements the interactive sessions of the graphene command
+package repl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"zimlit/graphene/ast"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/checker"
+	"zimlit/graphene/interp"
+	"zimlit/graphene/lexer"
+	"zimlit/graphene/parser"
+	"zimlit/graphene/token"
+)
+
+// Session keeps the bindings made by every input it evaluates, so an input
+// can use the fns and variables declared by the ones before it
+type Session struct {
+	checker checker.Checker
+	interp  interp.Interpreter
+	host    *builtins.Host
+	fname   string
+	// pending are the lines of an input that is not complete yet
+	pending []string
+}
+
+func NewSession(fname string) *Session {
+	s := &Session{
+		host:  &builtins.Host{Stdout: os.Stdout},
+		fname: fname,
+	}
+	s.Reset()
+	return s
+}
+
+// SetHost sets the host builtins run in
+func (s *Session) SetHost(h *builtins.Host) {
+	s.host = h
+	s.interp.SetHost(h)
+}
+
+// Reset discards every binding made by the session
+func (s *Session) Reset() {
+	s.pending = nil
+	s.checker = checker.NewChecker(nil, s.fname)
+	s.interp = interp.NewInterpreter(nil, s.fname)
+	s.interp.SetHost(s.host)
+}
+
+// Result is the value of an evaluated input
+type Result struct {
+	Value any
+	Kind  ast.ValueKind
+	// Show is false when the input does not end in an expression with a
+	// value, such as a declaration or a call of a fn returning nil
+	Show bool
+}
+
+func (r Result) String() string {
+	if !r.Show {
+		return ""
+	}
+	value := builtins.Format(r.Value)
+	if str, ok := r.Value.(string); ok {
+		value = strconv.Quote(str)
+	}
+	return fmt.Sprintf("=> %s : %s", value, r.Kind)
+}
+
+// Eval parses, checks and runs src. An input that fails leaves the session
+// as it was before it, apart from assignments made before a runtime error
+func (s *Session) Eval(ctx context.Context, src string) (Result, error) {
+	return s.eval(ctx, s.fname, src)
+}
+
+// Line evaluates line along with the lines before it that did not make a
+// complete input. While the input is not complete Line keeps its lines and
+// returns the error of parsing them, which parser.Incomplete reports
+func (s *Session) Line(ctx context.Context, line string) (Result, error) {
+	input := append(s.pending, line)
+	result, err := s.Eval(ctx, strings.Join(input, "\n"))
+	if parser.Incomplete(err) {
+		s.pending = input
+		return Result{}, err
+	}
+	s.pending = nil
+	return result, err
+}
+
+// Pending reports whether Line holds the lines of an incomplete input
+func (s *Session) Pending() bool {
+	return s.pending != nil
+}
+
+// Discard drops the lines of an incomplete input
+func (s *Session) Discard() {
+	s.pending = nil
+}
+
+// Prompt returns the prompt of the next line, which shows whether it
+// continues an incomplete input
+func (s *Session) Prompt() string {
+	if s.Pending() {
+		return "... "
+	}
+	return "> "
+}
+
+// Load evaluates the file at path in the session
+func (s *Session) Load(ctx context.Context, path string) (Result, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	return s.eval(ctx, path, string(buf))
+}
+
+func (s *Session) eval(ctx context.Context, fname string, src string) (Result, error) {
+	f, err := parser.ParseSource(ctx, fname, src)
+	if err != nil {
+		return Result{}, err
+	}
+	savedKinds := s.checker.SaveGlobals()
+	stmts, kind, err := s.checker.CheckInput(f.Stmts, f.Lines, fname)
+	if err != nil {
+		return Result{}, err
+	}
+
+	s.interp.BeginInput()
+	saved := s.interp.SaveGlobals()
+	s.interp.SetSource(f.Lines, fname)
+	value, err := s.interp.Interpret(stmts)
+	if err != nil {
+		s.interp.RestoreGlobals(saved)
+		s.checker.RestoreGlobals(savedKinds)
+		return Result{}, err
+	}
+
+	show := false
+	if len(stmts) > 0 {
+		_, isExpr := stmts[len(stmts)-1].(ast.ExprStmt)
+		show = isExpr && kind != ast.NIL
+	}
+	return Result{value, kind, show}, nil
+}
+
+// Type returns the kind of src without running it or keeping its bindings
+func (s *Session) Type(ctx context.Context, src string) (ast.ValueKind, error) {
+	f, err := parser.ParseSource(ctx, s.fname, src)
+	if err != nil {
+		return nil, err
+	}
+	saved := s.checker.SaveGlobals()
+	defer s.checker.RestoreGlobals(saved)
+
+	_, kind, err := s.checker.CheckInput(f.Stmts, f.Lines, s.fname)
+	return kind, err
+}
+
+// AST returns the statements of src as they are parsed
+func (s *Session) AST(ctx context.Context, src string) (ast.Stmts, error) {
+	f, err := parser.ParseSource(ctx, s.fname, src)
+	if err != nil {
+		return nil, err
+	}
+	return f.Stmts, nil
+}
+
+// Tokens returns the tokens of src
+func (s *Session) Tokens(src string) ([]token.Token, error) {
+	l := lexer.NewLexer(src, s.fname)
+	toks, _, errs := l.Lex()
+	if errs != nil {
+		return nil, &errs
+	}
+	return toks, nil
+}
+
+// Bindings returns the global bindings of the session sorted by name
+func (s *Session) Bindings() []checker.Binding {
+	return s.checker.Bindings()
+}
+
+// Complete returns the keywords, bound names and commands that start with
+// prefix, sorted
+func (s *Session) Complete(prefix string) []string {
+	var words []string
+	if strings.HasPrefix(prefix, ":") {
+		for _, c := range commands {
+			words = append(words, ":"+c.name)
+		}
+	} else {
+		words = lexer.Keywords()
+		for _, b := range s.Bindings() {
+			words = append(words, b.Name)
+		}
+	}
+
+	sort.Strings(words)
+	var matches []string
+	for i, w := range words {
+		// the conversion builtins are also keywords
+		if strings.HasPrefix(w, prefix) && (i == 0 || words[i-1] != w) {
+			matches = append(matches, w)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,149 @@
+/*
+	Copyright 2022 Devin Rockwell
+
+	This file is part of Graphene.
+
+	Graphene is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.
+
+	Graphene is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License along with Graphene. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package repl_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"zimlit/graphene/builtins"
+	"zimlit/graphene/interp"
+	"zimlit/graphene/parser"
+	"zimlit/graphene/repl"
+)
+
+// session returns a new session whose programs print to out
+func session(out *strings.Builder) *repl.Session {
+	s := repl.NewSession("stdin")
+	s.SetHost(&builtins.Host{Stdout: out})
+	return s
+}
+
+func TestSession(t *testing.T) {
+	var out strings.Builder
+	s := session(&out)
+	ctx := context.Background()
+	inputs := []struct {
+		src string
+		// want is the displayed result, err is a part of the error
+		want string
+		err  string
+	}{
+		{src: "let x = 1"},
+		{src: "fn double(n: int): int n * 2 end"},
+		{src: "double(x)", want: "=> 2 : int"},
+		{src: "let mut n = 0\nfn inc(): int\n\tn += 1\n\tn\nend"},
+		{src: "inc()\ninc()", want: "=> 2 : int"},
+		{src: "n", want: "=> 2 : int"},
+		{src: "\"a\\n\"", want: "=> \"a\\n\" : string"},
+		{src: "1.0 / 4.0", want: "=> 0.25 : float"},
+		{src: "2.0", want: "=> 2.0 : float"},
+		{src: "double", want: "=> <fn (fn int (int))> : (fn int (int))"},
+		{src: "println(\"hi\")"},
+		{src: "let y = 3\ny", want: "=> 3 : int"},
+		{src: "let x = \"shadowed\"\nx", want: "=> \"shadowed\" : string"},
+	}
+	for _, in := range inputs {
+		result, err := s.Eval(ctx, in.src)
+		if err != nil {
+			t.Fatalf("%q: %s", in.src, err)
+		}
+		if got := result.String(); got != in.want {
+			t.Errorf("%q displayed %q, want %q", in.src, got, in.want)
+		}
+	}
+	if out.String() != "hi\n" {
+		t.Errorf("printed %q, want %q", out.String(), "hi\n")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	var out strings.Builder
+	s := session(&out)
+	ctx := context.Background()
+	for _, src := range []string{"let mut n = 1", "let z = 0"} {
+		if _, err := s.Eval(ctx, src); err != nil {
+			t.Fatalf("%q: %s", src, err)
+		}
+	}
+
+	// a check error keeps nothing of the input
+	if _, err := s.Eval(ctx, "let a = 1\nn = \"s\""); err == nil {
+		t.Fatal("assigning a string to an int gave no error")
+	}
+	// a runtime error drops the declarations of the input but keeps the
+	// assignments made before it
+	_, err := s.Eval(ctx, "let b = 2\nn = 5\nlet mut n = \"shadow\"\nprintln(1 / z)")
+	if _, ok := err.(interp.RuntimeErr); !ok {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := s.Eval(ctx, name); err == nil || !strings.Contains(err.Error(), "undefined") {
+			t.Errorf("%s is still bound after a failed input, error %v", name, err)
+		}
+	}
+	result, err := s.Eval(ctx, "n")
+	if err != nil || result.String() != "=> 5 : int" {
+		t.Errorf("n is %s, %v, want => 5 : int", result, err)
+	}
+
+	_, err = s.Eval(ctx, "exit(3)")
+	if exit, ok := err.(builtins.Exit); !ok || exit.Code != 3 {
+		t.Errorf("exit(3) gave %v", err)
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	var out strings.Builder
+	s := session(&out)
+	ctx := context.Background()
+	lines := []struct {
+		line   string
+		prompt string
+		want   string
+	}{
+		{"fn f(x: int): int", "... ", ""},
+		{"\tif x > 0", "... ", ""},
+		{"\t\tx", "... ", ""},
+		{"\telse 0 end", "... ", ""},
+		{"end", "> ", ""},
+		{"f(", "... ", ""},
+		{"3)", "> ", "=> 3 : int"},
+		{"\"open", "> ", ""},
+	}
+	for _, l := range lines {
+		result, err := s.Line(ctx, l.line)
+		if l.prompt == "... " != parser.Incomplete(err) {
+			t.Fatalf("%q gave %v", l.line, err)
+		}
+		if got := s.Prompt(); got != l.prompt {
+			t.Errorf("prompt after %q is %q, want %q", l.line, got, l.prompt)
+		}
+		if got := result.String(); got != l.want {
+			t.Errorf("%q displayed %q, want %q", l.line, got, l.want)
+		}
+	}
+
+	// discarding an incomplete input starts a new one
+	if _, err := s.Line(ctx, "let y = (1 +"); !parser.Incomplete(err) {
+		t.Fatalf("got %v, want an incomplete input", err)
+	}
+	s.Discard()
+	if s.Pending() || s.Prompt() != "> " {
+		t.Fatal("lines still pending after Discard")
+	}
+	result, err := s.Line(ctx, "f(2)")
+	if err != nil || result.String() != "=> 2 : int" {
+		t.Errorf("f(2) gave %s, %v", result, err)
+	}
+}
@@ -53,6 +53,27 @@ const (
 	FN
 	COMMA
 	RETURN
+	IMPORT
+	PUB
+	DOT
+	LBRACKET
+	RBRACKET
+	PERCENT
+	AMP
+	PIPE
+	CARET
+	TILDE
+	SHL
+	SHR
+	PLUSEQ
+	MINUSEQ
+	STAREQ
+	SLASHEQ
+	PERCENTEQ
+	SEMICOLON
+	// ILLEGAL is text the lexer could not make a token of, it is reported
+	// as an error and lets the parser carry on
+	ILLEGAL
 )
 
 func (t TokenKind) String() string {
@@ -93,6 +114,8 @@ func (t TokenKind) String() string {
 		return "<="
 	case GREATEREQ:
 		return ">="
+	case BANG:
+		return "!"
 	case IDENT:
 		return "identifier"
 	case COLON:
@@ -119,6 +142,44 @@ func (t TokenKind) String() string {
 		return ","
 	case RETURN:
 		return "return"
+	case IMPORT:
+		return "import"
+	case PUB:
+		return "pub"
+	case DOT:
+		return "."
+	case LBRACKET:
+		return "["
+	case RBRACKET:
+		return "]"
+	case PERCENT:
+		return "%"
+	case AMP:
+		return "&"
+	case PIPE:
+		return "|"
+	case CARET:
+		return "^"
+	case TILDE:
+		return "~"
+	case SHL:
+		return "<<"
+	case SHR:
+		return ">>"
+	case PLUSEQ:
+		return "+="
+	case MINUSEQ:
+		return "-="
+	case STAREQ:
+		return "*="
+	case SLASHEQ:
+		return "/="
+	case PERCENTEQ:
+		return "%="
+	case SEMICOLON:
+		return ";"
+	case ILLEGAL:
+		return "illegal"
 	default:
 		return "INVALID"
 	}